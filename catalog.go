@@ -0,0 +1,430 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProgramInfo es un procedimiento o función del catálogo (una fila por sobrecarga)
+type ProgramInfo struct {
+	Schema   string `json:"schema"`
+	Package  string `json:"package,omitempty"`
+	Name     string `json:"name"`
+	FullName string `json:"full_name"` // Nombre a usar en /procedure (formatObjectName)
+	Type     string `json:"type"`      // PROCEDURE o FUNCTION
+	Overload int    `json:"overload,omitempty"`
+}
+
+// ProgramArgument describe un argumento según ALL_ARGUMENTS
+type ProgramArgument struct {
+	Name      string `json:"name,omitempty"`
+	Position  int    `json:"position"`
+	Direction string `json:"direction"`           // IN, OUT o IN/OUT
+	DataType  string `json:"data_type"`           // Tipo Oracle (NUMBER, VARCHAR2, TABLE, ...)
+	Type      string `json:"type,omitempty"`      // Tipo equivalente para /procedure
+	TypeName  string `json:"type_name,omitempty"` // Colecciones/objetos: tipo Oracle para "type_name"
+	Length    int    `json:"data_length,omitempty"`
+	Precision int    `json:"data_precision,omitempty"`
+	Scale     int    `json:"data_scale,omitempty"`
+	Defaulted bool   `json:"defaulted"`
+}
+
+// ProgramOverload es una de las firmas de un programa
+type ProgramOverload struct {
+	Overload int               `json:"overload,omitempty"`
+	Type     string            `json:"type"` // PROCEDURE o FUNCTION
+	Return   *ProgramArgument  `json:"return,omitempty"`
+	Params   []ProgramArgument `json:"params"`
+}
+
+// ProgramSignature es la firma completa de un procedimiento o función
+type ProgramSignature struct {
+	Schema    string            `json:"schema"`
+	Package   string            `json:"package,omitempty"`
+	Name      string            `json:"name"`
+	FullName  string            `json:"full_name"`
+	Overloads []ProgramOverload `json:"overloads"`
+}
+
+// programRef identifica un programa ya resuelto en el diccionario
+type programRef struct {
+	Schema  string
+	Package string
+	Name    string
+}
+
+// FullName retorna el nombre con las reglas de formatObjectName (SCHEMA.PAQUETE.NOMBRE)
+func (p programRef) FullName() string {
+	if p.Package != "" {
+		return formatObjectName(p.Schema, p.Package+"."+p.Name)
+	}
+	return formatObjectName(p.Schema, p.Name)
+}
+
+// currentSchema retorna el esquema de la sesión, usado cuando el nombre no lo incluye
+func currentSchema(ctx context.Context) (string, error) {
+	var schema string
+	err := db.QueryRowContext(ctx, "SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL").Scan(&schema)
+	return schema, err
+}
+
+// splitProgramName separa un nombre (con o sin comillas) en sus partes en mayúsculas
+func splitProgramName(schema, name string) []string {
+	parts := []string{}
+	if schema != "" {
+		parts = append(parts, strings.ToUpper(strings.Trim(schema, "\" ")))
+	}
+	for _, part := range strings.Split(name, ".") {
+		parts = append(parts, strings.ToUpper(strings.Trim(part, "\" ")))
+	}
+	return parts
+}
+
+// resolveProgram busca en ALL_PROCEDURES a qué programa se refiere el nombre recibido.
+// Con dos partes, "A.B" puede ser ESQUEMA.PROCEDIMIENTO o PAQUETE.PROCEDIMIENTO (en el esquema actual).
+func resolveProgram(ctx context.Context, schema, name string) (*programRef, error) {
+	parts := splitProgramName(schema, name)
+	candidates := []programRef{}
+	switch len(parts) {
+	case 1, 2:
+		current, err := currentSchema(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo obtener el esquema actual: %v", err)
+		}
+		if len(parts) == 1 {
+			candidates = append(candidates, programRef{Schema: current, Name: parts[0]})
+		} else {
+			candidates = append(candidates,
+				programRef{Schema: parts[0], Name: parts[1]},
+				programRef{Schema: current, Package: parts[0], Name: parts[1]})
+		}
+	case 3:
+		candidates = append(candidates, programRef{Schema: parts[0], Package: parts[1], Name: parts[2]})
+	default:
+		return nil, fmt.Errorf("nombre inválido '%s': usa NOMBRE, PAQUETE.NOMBRE o ESQUEMA.PAQUETE.NOMBRE", name)
+	}
+
+	for _, c := range candidates {
+		var count int
+		var err error
+		if c.Package == "" {
+			err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM ALL_PROCEDURES
+				WHERE OWNER = :1 AND OBJECT_NAME = :2 AND OBJECT_TYPE IN ('PROCEDURE', 'FUNCTION')`,
+				c.Schema, c.Name).Scan(&count)
+		} else {
+			err = db.QueryRowContext(ctx, `SELECT COUNT(*) FROM ALL_PROCEDURES
+				WHERE OWNER = :1 AND OBJECT_NAME = :2 AND PROCEDURE_NAME = :3`,
+				c.Schema, c.Package, c.Name).Scan(&count)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			ref := c
+			return &ref, nil
+		}
+	}
+	return nil, nil
+}
+
+// apiTypeFor traduce el tipo Oracle de un argumento al "type" que acepta /procedure
+func apiTypeFor(dataType string) string {
+	switch strings.ToUpper(dataType) {
+	case "NUMBER", "FLOAT", "INTEGER", "BINARY_INTEGER", "PLS_INTEGER", "BINARY_FLOAT", "BINARY_DOUBLE":
+		return "number"
+	case "DATE":
+		return "date"
+	case "TIMESTAMP":
+		return "timestamp"
+	case "TIMESTAMP WITH TIME ZONE", "TIMESTAMP WITH LOCAL TIME ZONE":
+		return "timestamptz"
+	case "INTERVAL DAY TO SECOND", "INTERVAL YEAR TO MONTH":
+		return "interval"
+	case "PL/SQL BOOLEAN", "BOOLEAN":
+		return "boolean"
+	case "TABLE", "VARRAY", "OBJECT", "PL/SQL TABLE", "PL/SQL RECORD", "REF CURSOR":
+		return ""
+	}
+	return "string"
+}
+
+// loadProgramSignature lee las sobrecargas y argumentos del programa desde ALL_ARGUMENTS
+func loadProgramSignature(ctx context.Context, ref *programRef) (*ProgramSignature, error) {
+	rows, err := db.QueryContext(ctx, `SELECT NVL(OVERLOAD, '0'), ARGUMENT_NAME, POSITION, IN_OUT, DATA_TYPE,
+			TYPE_OWNER, TYPE_NAME, TYPE_SUBNAME, DATA_LENGTH, DATA_PRECISION, DATA_SCALE, DEFAULTED
+		FROM ALL_ARGUMENTS
+		WHERE OWNER = :1 AND OBJECT_NAME = :2 AND NVL(PACKAGE_NAME, ' ') = NVL(:3, ' ') AND DATA_LEVEL = 0
+		ORDER BY TO_NUMBER(NVL(OVERLOAD, '0')), SEQUENCE`, ref.Schema, ref.Name, ref.Package)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sig := &ProgramSignature{
+		Schema:    ref.Schema,
+		Package:   ref.Package,
+		Name:      ref.Name,
+		FullName:  ref.FullName(),
+		Overloads: []ProgramOverload{},
+	}
+	byOverload := make(map[int]*ProgramOverload)
+	order := []int{}
+	for rows.Next() {
+		var overload string
+		var argName, inOut, dataType, typeOwner, typeName, typeSubname, defaulted sql.NullString
+		var position, length, precision, scale sql.NullInt64
+		if err := rows.Scan(&overload, &argName, &position, &inOut, &dataType,
+			&typeOwner, &typeName, &typeSubname, &length, &precision, &scale, &defaulted); err != nil {
+			return nil, err
+		}
+		n, _ := strconv.Atoi(overload)
+		ov, ok := byOverload[n]
+		if !ok {
+			ov = &ProgramOverload{Overload: n, Type: "PROCEDURE", Params: []ProgramArgument{}}
+			byOverload[n] = ov
+			order = append(order, n)
+		}
+		// Los programas sin argumentos tienen una fila sin tipo de dato
+		if !dataType.Valid {
+			continue
+		}
+		arg := ProgramArgument{
+			Name:      argName.String,
+			Position:  int(position.Int64),
+			Direction: inOut.String,
+			DataType:  dataType.String,
+			Type:      apiTypeFor(dataType.String),
+			Length:    int(length.Int64),
+			Precision: int(precision.Int64),
+			Scale:     int(scale.Int64),
+			Defaulted: defaulted.String == "Y",
+		}
+		if typeName.Valid {
+			parts := []string{}
+			if typeOwner.Valid {
+				parts = append(parts, typeOwner.String)
+			}
+			parts = append(parts, typeName.String)
+			if typeSubname.Valid {
+				parts = append(parts, typeSubname.String)
+			}
+			arg.TypeName = strings.Join(parts, ".")
+		}
+		if arg.Position == 0 {
+			ov.Type = "FUNCTION"
+			ov.Return = &arg
+			continue
+		}
+		ov.Params = append(ov.Params, arg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, n := range order {
+		sig.Overloads = append(sig.Overloads, *byOverload[n])
+	}
+	return sig, nil
+}
+
+// Cache de firmas: tiempo que se reutiliza una firma leída del diccionario y máximo de programas
+const (
+	signatureCacheTTL        = 5 * time.Minute
+	signatureCacheMaxEntries = 1000
+)
+
+// signatureEntry es una firma cacheada
+type signatureEntry struct {
+	sig      *ProgramSignature
+	loadedAt time.Time
+}
+
+// signatureCache evita consultar ALL_ARGUMENTS en cada llamada a /procedure
+var signatureCache = struct {
+	entries map[string]signatureEntry
+	mu      sync.Mutex
+}{entries: make(map[string]signatureEntry)}
+
+// lookupSignature resuelve el programa y retorna su firma (cacheada). Retorna nil si no existe.
+func lookupSignature(ctx context.Context, schema, name string) (*ProgramSignature, error) {
+	sig, _, err := cachedSignature(ctx, schema, name)
+	return sig, err
+}
+
+// signatureKey es la clave del programa en signatureCache
+func signatureKey(schema, name string) string {
+	return strings.ToUpper(schema + "|" + name)
+}
+
+// cachedSignature es lookupSignature indicando si la firma salió del cache (puede estar
+// desactualizada si el programa se recompiló). Los programas no encontrados no se cachean:
+// uno recién creado se encuentra en la siguiente llamada.
+func cachedSignature(ctx context.Context, schema, name string) (sig *ProgramSignature, cached bool, err error) {
+	key := signatureKey(schema, name)
+	signatureCache.mu.Lock()
+	entry, ok := signatureCache.entries[key]
+	signatureCache.mu.Unlock()
+	if ok && time.Since(entry.loadedAt) < signatureCacheTTL {
+		return entry.sig, true, nil
+	}
+
+	ref, err := resolveProgram(ctx, schema, name)
+	if err != nil || ref == nil {
+		return nil, false, err
+	}
+	if sig, err = loadProgramSignature(ctx, ref); err != nil {
+		return nil, false, err
+	}
+
+	now := time.Now()
+	signatureCache.mu.Lock()
+	defer signatureCache.mu.Unlock()
+	if len(signatureCache.entries) >= signatureCacheMaxEntries {
+		// Se quitan las vencidas y, si sigue lleno, la más antigua
+		oldestKey, oldest := "", now
+		for k, e := range signatureCache.entries {
+			if now.Sub(e.loadedAt) >= signatureCacheTTL {
+				delete(signatureCache.entries, k)
+			} else if e.loadedAt.Before(oldest) {
+				oldestKey, oldest = k, e.loadedAt
+			}
+		}
+		if len(signatureCache.entries) >= signatureCacheMaxEntries {
+			delete(signatureCache.entries, oldestKey)
+		}
+	}
+	signatureCache.entries[key] = signatureEntry{sig: sig, loadedAt: now}
+	return sig, false, nil
+}
+
+// invalidateSignature quita la firma del cache para leerla de nuevo en la próxima llamada
+func invalidateSignature(schema, name string) {
+	signatureCache.mu.Lock()
+	delete(signatureCache.entries, signatureKey(schema, name))
+	signatureCache.mu.Unlock()
+}
+
+// listPrograms lista procedimientos y funciones de ALL_PROCEDURES con filtros opcionales
+func listPrograms(ctx context.Context, schema, pkg, q string) ([]ProgramInfo, error) {
+	if schema == "" {
+		current, err := currentSchema(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("no se pudo obtener el esquema actual: %v", err)
+		}
+		schema = current
+	}
+
+	query := `SELECT p.OWNER,
+			CASE WHEN p.PROCEDURE_NAME IS NULL THEN NULL ELSE p.OBJECT_NAME END,
+			NVL(p.PROCEDURE_NAME, p.OBJECT_NAME),
+			NVL(p.OVERLOAD, '0'),
+			CASE WHEN EXISTS (SELECT 1 FROM ALL_ARGUMENTS a
+				WHERE a.OBJECT_ID = p.OBJECT_ID AND a.SUBPROGRAM_ID = p.SUBPROGRAM_ID
+				AND a.POSITION = 0 AND a.DATA_LEVEL = 0
+				AND NVL(a.OVERLOAD, '0') = NVL(p.OVERLOAD, '0'))
+			THEN 'FUNCTION' ELSE 'PROCEDURE' END
+		FROM ALL_PROCEDURES p
+		WHERE p.OWNER = :1
+		AND (p.OBJECT_TYPE IN ('PROCEDURE', 'FUNCTION') OR (p.OBJECT_TYPE = 'PACKAGE' AND p.PROCEDURE_NAME IS NOT NULL))`
+	args := []interface{}{strings.ToUpper(schema)}
+	if pkg != "" {
+		args = append(args, strings.ToUpper(pkg))
+		query += fmt.Sprintf(" AND p.OBJECT_TYPE = 'PACKAGE' AND p.OBJECT_NAME = :%d", len(args))
+	}
+	if q != "" {
+		args = append(args, "%"+strings.ToUpper(q)+"%")
+		query += fmt.Sprintf(" AND NVL(p.PROCEDURE_NAME, p.OBJECT_NAME) LIKE :%d", len(args))
+	}
+	query += " ORDER BY p.OBJECT_NAME, p.PROCEDURE_NAME, TO_NUMBER(NVL(p.OVERLOAD, '0'))"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	programs := []ProgramInfo{}
+	for rows.Next() {
+		var owner, name, overload, kind string
+		var pkgName sql.NullString
+		if err := rows.Scan(&owner, &pkgName, &name, &overload, &kind); err != nil {
+			return nil, err
+		}
+		n, _ := strconv.Atoi(overload)
+		ref := programRef{Schema: owner, Package: pkgName.String, Name: name}
+		programs = append(programs, ProgramInfo{
+			Schema:   owner,
+			Package:  pkgName.String,
+			Name:     name,
+			FullName: ref.FullName(),
+			Type:     kind,
+			Overload: n,
+		})
+	}
+	return programs, rows.Err()
+}
+
+// proceduresHandler expone el catálogo de programas:
+// GET /procedures?schema=&package=&q= y GET /procedures/{schema}.{package}.{name}
+func proceduresHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(&w, r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite GET"})
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/procedures")
+	name = strings.TrimPrefix(name, "/")
+
+	// Sin nombre: listar programas
+	if name == "" {
+		queryParams := r.URL.Query()
+		programs, err := listPrograms(r.Context(), queryParams.Get("schema"), queryParams.Get("package"), queryParams.Get("q"))
+		if err != nil {
+			log.Printf("[CATALOG] Error listando programas: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"total":      len(programs),
+			"procedures": programs,
+		})
+		return
+	}
+
+	// Con nombre: firma completa
+	ref, err := resolveProgram(r.Context(), "", name)
+	if err != nil {
+		log.Printf("[CATALOG] Error resolviendo '%s': %v", name, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if ref == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Programa '%s' no encontrado", name)})
+		return
+	}
+	sig, err := loadProgramSignature(r.Context(), ref)
+	if err != nil {
+		log.Printf("[CATALOG] Error leyendo firma de %s: %v", ref.FullName(), err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(sig)
+}
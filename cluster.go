@@ -0,0 +1,438 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Valores por defecto de la cola compartida entre instancias
+const (
+	defaultJobPollInterval = 2 * time.Second
+	jobClaimTimeout        = 30 * time.Second
+	jobClaimCandidates     = 3 // Candidatos leídos por cada job a tomar
+)
+
+// JobCluster coordina las instancias de la API que comparten ASYNC_JOBS, que es la fuente de
+// verdad de los jobs: cada instancia toma jobs pendientes sin dueño con SELECT ... FOR UPDATE
+// SKIP LOCKED, lee de la BD los jobs de las demás instancias y atiende las cancelaciones que
+// se piden desde otra instancia (CANCEL_REQUESTED). Sin la tabla, cada instancia usa solo su cola.
+type JobCluster struct {
+	enabled  bool          // ASYNC_JOBS disponible al iniciar
+	interval time.Duration // Cada cuánto se buscan jobs sin dueño y cancelaciones pedidas
+	wake     chan struct{} // Adelanta el próximo sondeo (p.ej. al liberarse un worker)
+	backlog  atomic.Int64  // Jobs pendientes sin dueño según el último sondeo
+}
+
+var jobCluster = &JobCluster{
+	interval: defaultJobPollInterval,
+	wake:     make(chan struct{}, 1),
+}
+
+// newJobClusterFromEnv arma la coordinación con ASYNC_POLL_INTERVAL; enabled indica si
+// ASYNC_JOBS está disponible
+func newJobClusterFromEnv(enabled bool) (*JobCluster, error) {
+	c := &JobCluster{
+		enabled:  enabled,
+		interval: defaultJobPollInterval,
+		wake:     make(chan struct{}, 1),
+	}
+	if value := strings.TrimSpace(os.Getenv("ASYNC_POLL_INTERVAL")); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("ASYNC_POLL_INTERVAL debe ser una duración positiva (p.ej. 2s)")
+		}
+		c.interval = d
+	}
+	return c, nil
+}
+
+// shared indica si la cola se comparte a través de ASYNC_JOBS
+func (c *JobCluster) shared() bool {
+	return c.enabled && db != nil
+}
+
+// Wake adelanta el próximo sondeo sin bloquear
+func (c *JobCluster) Wake() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Backlog retorna los jobs pendientes que todavía no tomó ninguna instancia
+func (c *JobCluster) Backlog() int {
+	return int(c.backlog.Load())
+}
+
+// Start lanza el sondeo de la cola compartida y la búsqueda periódica de jobs huérfanos
+// de instancias que se detuvieron (ver orphans.go)
+func (c *JobCluster) Start() {
+	if !c.shared() {
+		return
+	}
+	go func() {
+		poll := time.NewTicker(c.interval)
+		defer poll.Stop()
+		orphans := time.NewTicker(jobRecovery.orphanTimeout / 2)
+		defer orphans.Stop()
+		for {
+			select {
+			case <-poll.C:
+			case <-c.wake:
+			case <-orphans.C:
+				jobManager.syncFromDB()
+				jobRecovery.RecoverOrphanedJobs(false)
+			}
+			c.poll()
+		}
+	}()
+	c.Wake()
+	log.Printf("[JOBS] Cola compartida en ASYNC_JOBS como instancia %s (sondeo cada %v)", jobRecovery.owner, c.interval)
+}
+
+func (c *JobCluster) poll() {
+	c.claim(jobQueue.IdleSlots())
+	c.handleCancelRequests()
+	c.refreshWatched()
+	c.advanceWorkflows()
+	c.countBacklog()
+}
+
+// claim toma hasta n jobs pendientes sin dueño, en orden de prioridad (con aging) y de creación,
+// y los encola en esta instancia. SKIP LOCKED saltea las filas que otra instancia está tomando.
+func (c *JobCluster) claim(n int) {
+	if n <= 0 {
+		return
+	}
+	now := time.Now()
+	order := "PRIORITY DESC"
+	args := []interface{}{now}
+	if jobQueue.aging > 0 {
+		agingSecs := int(jobQueue.aging / time.Second)
+		if agingSecs < 1 {
+			agingSecs = 1
+		}
+		order = fmt.Sprintf("PRIORITY + FLOOR((CAST(:2 AS DATE) - CAST(START_TIME AS DATE)) * 86400 / %d) DESC", agingSecs)
+		args = append(args, now)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jobClaimTimeout)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		log.Printf("[JOBS] Error iniciando la toma de jobs: %v", err)
+		return
+	}
+	defer tx.Rollback()
+
+	// Candidatos en orden de prioridad, acotados con ROWNUM (FOR UPDATE no admite ROWNUM ni FETCH
+	// FIRST junto con ORDER BY). Se piden más de los necesarios porque otras instancias pueden
+	// tomar algunos antes; cada uno se bloquea después por separado.
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT JOB_ID FROM (
+			SELECT JOB_ID FROM ASYNC_JOBS
+			WHERE STATUS = 'pending' AND INSTANCE_NAME IS NULL AND WORKFLOW IS NULL
+			  AND (NEXT_RETRY IS NULL OR NEXT_RETRY <= :1)
+			ORDER BY %s, START_TIME
+		) WHERE ROWNUM <= %d`, order, n*jobClaimCandidates), args...)
+	if err != nil {
+		log.Printf("[JOBS] Error buscando jobs sin dueño: %v", err)
+		return
+	}
+	candidates := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			candidates = append(candidates, id)
+		}
+	}
+	rows.Close()
+
+	ids := []string{}
+	for _, id := range candidates {
+		if len(ids) >= n {
+			break
+		}
+		// Bloquea solo esta fila; si otra instancia la tiene bloqueada o ya la tomó, se salta
+		var locked string
+		err := tx.QueryRowContext(ctx, `SELECT JOB_ID FROM ASYNC_JOBS
+			WHERE JOB_ID = :1 AND STATUS = 'pending' AND INSTANCE_NAME IS NULL
+			FOR UPDATE SKIP LOCKED`, id).Scan(&locked)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			log.Printf("[JOBS] Error bloqueando el job %s: %v", id, err)
+			return
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE ASYNC_JOBS SET INSTANCE_NAME = :1, HEARTBEAT = :2 WHERE JOB_ID = :3",
+			jobRecovery.owner, now, id); err != nil {
+			log.Printf("[JOBS] Error tomando el job %s: %v", id, err)
+			return
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		log.Printf("[JOBS] Error confirmando la toma de jobs: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		job, err := loadJobFromDB(id)
+		if err != nil {
+			log.Printf("[JOBS] Error leyendo el job tomado %s: %v", id, err)
+			continue
+		}
+		jobManager.store(job)
+		if stored, ok := jobManager.GetJob(id); ok {
+			enqueueStoredJob(stored)
+		}
+	}
+	log.Printf("[JOBS] %d jobs de la cola compartida tomados", len(ids))
+}
+
+// countBacklog actualiza la cantidad de jobs pendientes sin dueño (ver JobQueue.Full)
+func (c *JobCluster) countBacklog() {
+	var count int64
+	if err := db.QueryRow("SELECT COUNT(*) FROM ASYNC_JOBS WHERE STATUS = 'pending' AND INSTANCE_NAME IS NULL").Scan(&count); err != nil {
+		log.Printf("[JOBS] Error contando jobs sin dueño: %v", err)
+		return
+	}
+	c.backlog.Store(count)
+}
+
+// transfer cambia la instancia dueña de un job si en la BD sigue con el dueño y el estado
+// que se leyeron; to vacío lo devuelve a la cola compartida. Si el dueño es otra instancia,
+// solo se transfiere si esa instancia no volvió a actualizar el heartbeat.
+func (c *JobCluster) transfer(job *AsyncJob, to string, now time.Time) bool {
+	if !c.shared() {
+		return true
+	}
+	query := `UPDATE ASYNC_JOBS SET INSTANCE_NAME = :1, HEARTBEAT = :2
+		WHERE JOB_ID = :3 AND STATUS = :4 AND NVL(INSTANCE_NAME, ' ') = NVL(:5, ' ')`
+	args := []interface{}{to, now, job.ID, string(job.Status), job.Instance}
+	if job.Instance != "" && job.Instance != jobRecovery.owner {
+		query += " AND (HEARTBEAT IS NULL OR HEARTBEAT < :6)"
+		args = append(args, now.Add(-jobRecovery.orphanTimeout))
+	}
+	res, err := db.Exec(query, args...)
+	if err != nil {
+		log.Printf("[JOBS] Error transfiriendo el job %s: %v", job.ID, err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n == 1
+}
+
+// claimUnclaimed toma un job pendiente sin dueño para cancelarlo desde esta instancia
+func (c *JobCluster) claimUnclaimed(id string) bool {
+	now := time.Now()
+	if !c.transfer(&AsyncJob{ID: id, Status: JobStatusPending}, jobRecovery.owner, now) {
+		return false
+	}
+	jobManager.UpdateJob(id, func(j *AsyncJob) {
+		j.Instance = jobRecovery.owner
+		j.Heartbeat = &now
+	})
+	return true
+}
+
+// requestCancel pide la cancelación de un job a la instancia que lo ejecuta
+func (c *JobCluster) requestCancel(id string) bool {
+	res, err := db.Exec(`UPDATE ASYNC_JOBS SET CANCEL_REQUESTED = :1
+		WHERE JOB_ID = :2 AND STATUS IN ('pending', 'running')`, time.Now(), id)
+	if err != nil {
+		log.Printf("[JOBS] Error pidiendo la cancelación del job %s: %v", id, err)
+		return false
+	}
+	n, _ := res.RowsAffected()
+	return n == 1
+}
+
+// handleCancelRequests atiende las cancelaciones pedidas desde otras instancias para los jobs
+// de esta instancia
+func (c *JobCluster) handleCancelRequests() {
+	rows, err := db.Query("SELECT JOB_ID FROM ASYNC_JOBS WHERE INSTANCE_NAME = :1 AND CANCEL_REQUESTED IS NOT NULL",
+		jobRecovery.owner)
+	if err != nil {
+		log.Printf("[JOBS] Error buscando cancelaciones pedidas: %v", err)
+		return
+	}
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		if _, err := db.Exec("UPDATE ASYNC_JOBS SET CANCEL_REQUESTED = NULL WHERE JOB_ID = :1", id); err != nil {
+			log.Printf("[JOBS] Error registrando la cancelación del job %s: %v", id, err)
+			continue
+		}
+		if status, _ := cancelJob(id); status == http.StatusOK || status == http.StatusAccepted {
+			log.Printf("[JOBS] Job %s cancelado a pedido de otra instancia", id)
+		}
+	}
+}
+
+// refreshWatched actualiza desde la BD los jobs de otras instancias con suscriptores
+// (GET /jobs/{id}/events o ?wait=), para que reciban sus cambios
+func (c *JobCluster) refreshWatched() {
+	jobManager.mu.RLock()
+	ids := []string{}
+	for id := range jobManager.subs {
+		if job, ok := jobManager.jobs[id]; ok && !jobRecovery.owns(job) {
+			ids = append(ids, id)
+		}
+	}
+	jobManager.mu.RUnlock()
+	for _, id := range ids {
+		jobManager.refreshFromDB(id)
+	}
+}
+
+// advanceWorkflows avanza los workflows de esta instancia cuyos pasos terminaron en otra instancia
+func (c *JobCluster) advanceWorkflows() {
+	for _, job := range jobManager.GetAllJobs() {
+		snap := job.snapshotLocked()
+		if snap.Workflow == nil || snap.Status != JobStatusRunning || !jobRecovery.owns(&snap) {
+			continue
+		}
+		changed := false
+		for _, s := range snap.Workflow.Steps {
+			if s.JobID == "" || s.isFinal() {
+				continue
+			}
+			child, ok := jobManager.GetJob(s.JobID)
+			if ok && jobRecovery.owns(child) {
+				continue
+			}
+			if updated, _ := jobManager.refreshFromDB(s.JobID); updated {
+				changed = true
+			}
+		}
+		if changed {
+			workflowManager.advance(snap.ID)
+		}
+	}
+}
+
+// loadJobFromDB lee un job de ASYNC_JOBS; retorna sql.ErrNoRows si no existe
+func loadJobFromDB(id string) (*AsyncJob, error) {
+	jobs, err := queryJobs("JOB_ID = :1", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return jobs[0], nil
+}
+
+// store guarda en memoria un job leído de la BD y avisa a sus suscriptores si cambió.
+// Los jobs que esta instancia ya tiene como propios no se reemplazan: la memoria es su fuente.
+func (jm *JobManager) store(job *AsyncJob) (changed bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	current, exists := jm.jobs[job.ID]
+	if !exists {
+		jm.jobs[job.ID] = job
+		return true
+	}
+	if jobRecovery.owns(current) {
+		return false
+	}
+	changed = current.Status != job.Status || current.Progress != job.Progress ||
+		current.ProgressMessage != job.ProgressMessage || current.Instance != job.Instance
+	*current = *job
+	if changed {
+		jm.notify(current)
+	}
+	return changed
+}
+
+// refreshFromDB actualiza un job de otra instancia desde la BD. exists es false si el job
+// ya no existe (se quita de memoria).
+func (jm *JobManager) refreshFromDB(id string) (changed bool, exists bool) {
+	job, err := loadJobFromDB(id)
+	if err == sql.ErrNoRows {
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+		if current, ok := jm.jobs[id]; ok && !jobRecovery.owns(current) {
+			delete(jm.jobs, id)
+			jm.closeSubscriptions(id)
+			return true, false
+		}
+		_, ok := jm.jobs[id]
+		return false, ok
+	}
+	if err != nil {
+		log.Printf("[JOBS] Error leyendo el job %s de BD: %v", id, err)
+		_, ok := jm.GetJob(id)
+		return false, ok
+	}
+	return jm.store(job), true
+}
+
+// Lookup busca un job para la API: los de esta instancia en memoria y los demás en ASYNC_JOBS,
+// así cualquier instancia responde por cualquier job
+func (jm *JobManager) Lookup(id string) (*AsyncJob, bool) {
+	job, exists := jm.GetJob(id)
+	if !jobCluster.shared() {
+		return job, exists
+	}
+	if exists {
+		jm.mu.RLock()
+		owned := jobRecovery.owns(job)
+		jm.mu.RUnlock()
+		if owned {
+			return job, true
+		}
+	}
+	if _, exists := jm.refreshFromDB(id); !exists {
+		return nil, false
+	}
+	return jm.GetJob(id)
+}
+
+// syncFromDB actualiza en memoria los jobs recientes de todas las instancias (DELETE /jobs) y quita
+// los de otras instancias que ya no están en ASYNC_JOBS
+func (jm *JobManager) syncFromDB() {
+	if !jobCluster.shared() {
+		return
+	}
+	jobs, err := queryJobs(recentJobsFilter)
+	if err != nil {
+		log.Printf("[JOBS] Error sincronizando jobs desde BD: %v", err)
+		return
+	}
+	found := make(map[string]bool, len(jobs))
+	for _, job := range jobs {
+		found[job.ID] = true
+		jm.store(job)
+	}
+
+	cutoff := time.Now().Add(-24 * time.Hour)
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	for id, job := range jm.jobs {
+		recent := !job.Status.isFinal() || job.StartTime.After(cutoff)
+		if !found[id] && recent && !jobRecovery.owns(job) {
+			delete(jm.jobs, id)
+			jm.closeSubscriptions(id)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule es una expresión cron estándar de 5 campos (minuto hora día-mes mes día-semana)
+// evaluada en una zona horaria. Cada campo es un bitmask de los valores permitidos.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool // El campo era '*' (afecta cómo se combinan día del mes y de la semana)
+	loc                           *time.Location
+}
+
+// cronField describe el rango y los nombres aceptados de un campo
+type cronField struct {
+	name     string
+	min, max int
+	names    map[string]int
+}
+
+var (
+	cronMinute = cronField{name: "minuto", min: 0, max: 59}
+	cronHour   = cronField{name: "hora", min: 0, max: 23}
+	cronDom    = cronField{name: "día del mes", min: 1, max: 31}
+	cronMonth  = cronField{name: "mes", min: 1, max: 12, names: map[string]int{
+		"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+		"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+	}}
+	cronDow = cronField{name: "día de la semana", min: 0, max: 7, names: map[string]int{
+		"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+	}}
+)
+
+// cronMacros son los atajos aceptados en lugar de los 5 campos
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron interpreta una expresión cron ("0 2 * * 1-5", "*/15 * * * *", "@daily") en la zona indicada
+func parseCron(spec string, loc *time.Location) (*cronSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expresión cron inválida '%s': se esperan 5 campos (minuto hora día-mes mes día-semana) o @daily, @hourly, ...", spec)
+	}
+
+	c := &cronSchedule{loc: loc}
+	var err error
+	if c.minute, err = parseCronField(fields[0], cronMinute); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], cronHour); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], cronDom); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], cronMonth); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], cronDow); err != nil {
+		return nil, err
+	}
+	// 7 también es domingo
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.domStar = fields[2] == "*" || fields[2] == "?"
+	c.dowStar = fields[4] == "*" || fields[4] == "?"
+	return c, nil
+}
+
+// parseCronField interpreta un campo: '*', valores, rangos (a-b), pasos (*/n, a-b/n) y listas separadas por coma
+func parseCronField(expr string, f cronField) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		rangeExpr, step := part, 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("paso inválido en %s: '%s'", f.name, part)
+			}
+			rangeExpr, step = part[:idx], n
+		}
+
+		var low, high int
+		if rangeExpr == "*" || rangeExpr == "?" {
+			low, high = f.min, f.max
+		} else {
+			bounds := strings.SplitN(rangeExpr, "-", 2)
+			var err error
+			if low, err = cronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = cronValue(bounds[1], f); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				// "a/n" equivale a "a-max/n"
+				high = f.max
+			}
+			if high < low {
+				return 0, fmt.Errorf("rango inválido en %s: '%s'", f.name, part)
+			}
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// cronValue convierte un número o nombre (JAN, MON, ...) validando el rango del campo
+func cronValue(s string, f cronField) (int, error) {
+	if v, ok := f.names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("valor inválido en %s: '%s' (rango %d-%d)", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// dayMatches aplica la regla estándar de cron: si día del mes y día de la semana están
+// restringidos basta con que se cumpla uno; si no, se usa el que esté restringido
+func (c *cronSchedule) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+// Next retorna la primera ejecución estrictamente posterior a t (cero si no hay ninguna en 5 años)
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	t = t.In(c.loc)
+	// Siguiente minuto exacto
+	t = t.Add(time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	yearLimit := t.Year() + 5
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+	for c.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !c.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for c.hour&(1<<uint(t.Hour())) == 0 {
+		t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for c.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	return t
+}
+
+// NextRuns retorna las próximas n ejecuciones posteriores a t
+func (c *cronSchedule) NextRuns(t time.Time, n int) []time.Time {
+	runs := []time.Time{}
+	for len(runs) < n {
+		t = c.Next(t)
+		if t.IsZero() {
+			break
+		}
+		runs = append(runs, t)
+	}
+	return runs
+}
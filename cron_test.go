@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"* * * FOO *",
+	} {
+		if _, err := parseCron(spec, time.UTC); err == nil {
+			t.Errorf("parseCron(%q): se esperaba error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2025, 1, 15, 10, 7, 30, 0, time.UTC) // miércoles
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"*/15 * * * *", from, time.Date(2025, 1, 15, 10, 15, 0, 0, time.UTC)},
+		{"@daily", from, time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)},
+		{"0 2 * * 1-5", from, time.Date(2025, 1, 16, 2, 0, 0, 0, time.UTC)},
+		// "a/n" equivale a "a-max/n"
+		{"50/5 * * * *", from, time.Date(2025, 1, 15, 10, 50, 0, 0, time.UTC)},
+		{"0 20/2 * * *", from, time.Date(2025, 1, 15, 20, 0, 0, 0, time.UTC)},
+		// 7 y SUN son domingo
+		{"0 0 * * 7", from, time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * SUN", from, time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		// Día del mes y de la semana restringidos: basta con uno (el 1 o los viernes)
+		{"0 0 1 * FRI", from, time.Date(2025, 1, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * FRI", time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		// Con uno de los dos en '*' se usa el otro
+		{"0 0 20 * *", from, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)},
+		// Cambio de año
+		{"30 9 1 JAN *", from, time.Date(2026, 1, 1, 9, 30, 0, 0, time.UTC)},
+		{"0 0 * * *", time.Date(2025, 12, 31, 23, 59, 0, 0, time.UTC), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Estrictamente posterior
+		{"0 * * * *", time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC), time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)},
+		// 29 de febrero: siguiente año bisiesto
+		{"0 0 29 2 *", from, time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Nunca ocurre
+		{"0 0 31 2 *", from, time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := parseCron(tt.spec, time.UTC)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.spec, err)
+		}
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%v) = %v, se esperaba %v", tt.spec, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestCronNextLocation(t *testing.T) {
+	loc := time.FixedZone("UTC-3", -3*3600)
+	c, err := parseCron("0 2 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Next(time.Date(2025, 1, 15, 4, 0, 0, 0, time.UTC)) // 01:00 en UTC-3
+	if want := time.Date(2025, 1, 15, 5, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, se esperaba %v", got, want)
+	}
+}
+
+func TestCronNextRuns(t *testing.T) {
+	c, err := parseCron("0 9,18 * * MON-FRI", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runs := c.NextRuns(time.Date(2025, 1, 17, 12, 0, 0, 0, time.UTC), 3) // viernes
+	want := []time.Time{
+		time.Date(2025, 1, 17, 18, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 20, 9, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 20, 18, 0, 0, 0, time.UTC),
+	}
+	if len(runs) != len(want) {
+		t.Fatalf("NextRuns = %v, se esperaba %v", runs, want)
+	}
+	for i := range want {
+		if !runs[i].Equal(want[i]) {
+			t.Errorf("NextRuns[%d] = %v, se esperaba %v", i, runs[i], want[i])
+		}
+	}
+}
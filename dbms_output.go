@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// dbmsOutputChunk es la cantidad de líneas pedidas a DBMS_OUTPUT.GET_LINES por llamada
+const dbmsOutputChunk = 500
+
+// beginOutputCapture reserva una conexión del pool y habilita DBMS_OUTPUT en ella.
+// La llamada y la lectura posterior deben hacerse sobre la misma conexión (el buffer es por sesión).
+func beginOutputCapture(ctx context.Context) (*sql.Conn, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("no se pudo obtener conexión: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, "BEGIN DBMS_OUTPUT.ENABLE(NULL); END;"); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("no se pudo habilitar DBMS_OUTPUT: %v", err)
+	}
+	return conn, nil
+}
+
+// readDBMSOutput drena DBMS_OUTPUT.GET_LINES y deshabilita el buffer antes de devolver la conexión al pool
+func readDBMSOutput(ctx context.Context, conn *sql.Conn) ([]string, error) {
+	defer conn.ExecContext(ctx, "BEGIN DBMS_OUTPUT.DISABLE; END;")
+
+	linesType, err := udtTypes.Resolve("SYS.DBMSOUTPUT_LINESARRAY")
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+	for {
+		arg, ptr := linesType.OutArg()
+		numLines := dbmsOutputChunk
+		if _, err := conn.ExecContext(ctx, "BEGIN DBMS_OUTPUT.GET_LINES(:1, :2); END;",
+			arg, sql.Out{Dest: &numLines, In: true}); err != nil {
+			return lines, fmt.Errorf("error leyendo DBMS_OUTPUT: %v", err)
+		}
+		chunk := ptr.Elem()
+		for i := 0; i < chunk.Len() && i < numLines; i++ {
+			lines = append(lines, chunk.Index(i).Interface().(sql.NullString).String)
+		}
+		if numLines < dbmsOutputChunk {
+			return lines, nil
+		}
+	}
+}
+
+// prepareCall prepara la sentencia en la conexión dedicada si existe, o en el pool
+func prepareCall(ctx context.Context, conn *sql.Conn, call string) (*sql.Stmt, error) {
+	if conn != nil {
+		return conn.PrepareContext(ctx, call)
+	}
+	return db.PrepareContext(ctx, call)
+}
+
+// execOn ejecuta la sentencia en la conexión de captura si existe, o en el pool
+func execOn(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) (sql.Result, error) {
+	if conn != nil {
+		return conn.ExecContext(ctx, query, args...)
+	}
+	return db.ExecContext(ctx, query, args...)
+}
+
+// queryOn ejecuta la consulta en la conexión de captura si existe, o en el pool
+func queryOn(ctx context.Context, conn *sql.Conn, query string, args ...interface{}) (*sql.Rows, error) {
+	if conn != nil {
+		return conn.QueryContext(ctx, query, args...)
+	}
+	return db.QueryContext(ctx, query, args...)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Límites de GET /jobs/{id}/events y GET /jobs/{id}?wait=
+const (
+	jobEventsHeartbeat = 15 * time.Second // Comentario SSE para mantener viva la conexión en proxies
+	maxJobWait         = 2 * time.Minute  // Máximo de ?wait= en long polling
+)
+
+// snapshot copia el job para leerlo fuera del lock de JobManager
+func (job *AsyncJob) snapshot() AsyncJob {
+	snap := *job
+	if job.Webhook != nil {
+		webhook := *job.Webhook
+		webhook.Attempts = append([]WebhookAttempt(nil), job.Webhook.Attempts...)
+		snap.Webhook = &webhook
+	}
+	if job.Workflow != nil {
+		snap.Workflow = job.Workflow.copy()
+	}
+	return snap
+}
+
+// snapshotLocked copia el job tomando el lock de JobManager
+func (job *AsyncJob) snapshotLocked() AsyncJob {
+	jobManager.mu.RLock()
+	defer jobManager.mu.RUnlock()
+	return job.snapshot()
+}
+
+// Subscribe registra un suscriptor a los cambios de un job. El canal recibe una copia del job
+// en cada UpdateJob (si el suscriptor se atrasa solo conserva la más reciente) y se cierra
+// cuando el job se elimina o la API se detiene. unsubscribe debe llamarse al terminar.
+func (jm *JobManager) Subscribe(id string) (current AsyncJob, updates <-chan AsyncJob, unsubscribe func(), ok bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, exists := jm.jobs[id]
+	if !exists {
+		return AsyncJob{}, nil, nil, false
+	}
+	ch := make(chan AsyncJob, 1)
+	if jm.subs[id] == nil {
+		jm.subs[id] = make(map[chan AsyncJob]struct{})
+	}
+	jm.subs[id][ch] = struct{}{}
+
+	unsubscribe = func() {
+		jm.mu.Lock()
+		defer jm.mu.Unlock()
+		if _, active := jm.subs[id][ch]; active {
+			delete(jm.subs[id], ch)
+			if len(jm.subs[id]) == 0 {
+				delete(jm.subs, id)
+			}
+		}
+	}
+	return job.snapshot(), ch, unsubscribe, true
+}
+
+// notify envía el estado actual del job a sus suscriptores sin bloquear; debe llamarse con jm.mu tomado
+func (jm *JobManager) notify(job *AsyncJob) {
+	if len(jm.subs[job.ID]) == 0 {
+		return
+	}
+	snap := job.snapshot()
+	for ch := range jm.subs[job.ID] {
+		select {
+		case ch <- snap:
+		default:
+			// Reemplazar el estado pendiente de leer por el más reciente
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- snap
+		}
+	}
+}
+
+// closeSubscriptions cierra los canales de los suscriptores de un job; debe llamarse con jm.mu tomado
+func (jm *JobManager) closeSubscriptions(id string) {
+	for ch := range jm.subs[id] {
+		close(ch)
+	}
+	delete(jm.subs, id)
+}
+
+// CloseAllSubscriptions cierra todos los streams abiertos (al detener la API)
+func (jm *JobManager) CloseAllSubscriptions() {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+	for id := range jm.subs {
+		jm.closeSubscriptions(id)
+	}
+}
+
+// jobEventsHandler transmite los cambios de un job como Server-Sent Events (GET /jobs/{id}/events).
+// Eventos: "status" al cambiar de estado (incluye el estado inicial), "progress" al cambiar solo
+// el progreso y "result" cuando el job termina, tras el cual se cierra el stream. Cada evento
+// lleva el job completo, igual que GET /jobs/{id}.
+func jobEventsHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite GET"})
+		return
+	}
+	jobManager.Lookup(id) // Trae de ASYNC_JOBS los jobs de otras instancias
+	current, updates, unsubscribe, ok := jobManager.Subscribe(id)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Job no encontrado"})
+		return
+	}
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Evitar buffering en nginx
+	w.WriteHeader(http.StatusOK)
+
+	seq := 0
+	send := func(event string, data interface{}) bool {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("[JOBS] Error serializando evento de %s: %v", id, err)
+			return false
+		}
+		seq++
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", seq, event, payload); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if current.Status.isFinal() {
+		send("result", current)
+		return
+	}
+	if !send("status", current) {
+		return
+	}
+
+	heartbeat := time.NewTicker(jobEventsHeartbeat)
+	defer heartbeat.Stop()
+	last := current
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case job, open := <-updates:
+			if !open {
+				if _, exists := jobManager.GetJob(id); !exists {
+					send("deleted", map[string]string{"job_id": id})
+				}
+				return
+			}
+			switch {
+			case job.Status.isFinal():
+				send("result", job)
+				return
+			case job.Status != last.Status:
+				if !send("status", job) {
+					return
+				}
+			case job.Progress != last.Progress || job.ProgressMessage != last.ProgressMessage:
+				if !send("progress", job) {
+					return
+				}
+			}
+			last = job
+		}
+	}
+}
+
+// parseJobWait interpreta ?wait= de GET /jobs/{id}: una duración (30s, 1m) o segundos
+func parseJobWait(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	wait, err := time.ParseDuration(value)
+	if err != nil {
+		var secs int
+		secs, err = strconv.Atoi(value)
+		wait = time.Duration(secs) * time.Second
+	}
+	if err != nil || wait < 0 {
+		return 0, fmt.Errorf("'wait' debe ser una duración (p.ej. 30s) o segundos")
+	}
+	if wait > maxJobWait {
+		wait = maxJobWait
+	}
+	return wait, nil
+}
+
+// waitForJobChange espera hasta que el job cambie o termine, o hasta agotar wait (long polling).
+// Si el job ya terminó retorna de inmediato. changed indica si hubo un cambio durante la espera.
+func waitForJobChange(r *http.Request, id string, wait time.Duration) (job AsyncJob, changed bool, ok bool) {
+	jobManager.Lookup(id)
+	current, updates, unsubscribe, ok := jobManager.Subscribe(id)
+	if !ok {
+		return AsyncJob{}, false, false
+	}
+	defer unsubscribe()
+	if current.Status.isFinal() || wait == 0 {
+		return current, false, true
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return current, false, true
+		case <-timer.C:
+			return current, false, true
+		case job, open := <-updates:
+			if !open {
+				// Job eliminado o API deteniéndose
+				if _, exists := jobManager.GetJob(id); !exists {
+					return AsyncJob{}, false, false
+				}
+				return current, false, true
+			}
+			// Solo cuentan los cambios visibles para el cliente
+			if job.Status != current.Status || job.Progress != current.Progress ||
+				job.ProgressMessage != current.ProgressMessage || job.Status.isFinal() {
+				return job, true, true
+			}
+			current = job
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tamaño de página por defecto y máximo de GET /jobs
+const (
+	jobListDefaultLimit = 100
+	jobListMaxLimit     = 1000
+)
+
+// jobSortColumns son los campos por los que se puede ordenar GET /jobs (?sort=)
+var jobSortColumns = map[string]string{
+	"start_time":     "START_TIME",
+	"priority":       "NVL(PRIORITY, 5)",
+	"procedure_name": "PROCEDURE_NAME",
+	"status":         "STATUS",
+}
+
+// jobListQuery es la consulta de GET /jobs ya validada
+type jobListQuery struct {
+	where   []string               // Condiciones de los filtros
+	args    []interface{}          // Valores de los filtros
+	matches []func(*AsyncJob) bool // Los mismos filtros para los jobs en memoria (ver runInMemory)
+	sort    string                 // Campo de jobSortColumns
+	desc    bool
+	limit   int
+	cursor  *jobCursor // Continúa después de este job (paginación por cursor)
+	summary bool       // Omite params y result
+}
+
+// jobListFields leen de un job los campos de los filtros por igualdad
+var jobListFields = map[string]func(*AsyncJob) string{
+	"instance":     func(job *AsyncJob) string { return job.Instance },
+	"submitted_by": func(job *AsyncJob) string { return job.SubmittedBy },
+	"parent_id":    func(job *AsyncJob) string { return job.ParentID },
+}
+
+// jobCursor identifica el último job de una página: el valor del campo de orden y su ID.
+// Viaja en ?cursor= como JSON en base64 y solo vale para el mismo orden.
+type jobCursor struct {
+	Sort  string      `json:"s"`
+	Desc  bool        `json:"d"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// bind agrega un valor a los argumentos y retorna su placeholder
+func (q *jobListQuery) bind(value interface{}) string {
+	q.args = append(q.args, value)
+	return fmt.Sprintf(":%d", len(q.args))
+}
+
+// parseJobListQuery valida los parámetros de GET /jobs:
+// status, procedure, schema, instance, submitted_by, parent_id, from, to, sort, order, limit, cursor y summary
+func parseJobListQuery(values url.Values) (*jobListQuery, error) {
+	q := &jobListQuery{sort: "start_time", desc: true, limit: jobListDefaultLimit}
+
+	if status := values.Get("status"); status != "" {
+		var placeholders, statuses []string
+		for _, st := range strings.Split(status, ",") {
+			st = strings.ToLower(strings.TrimSpace(st))
+			valid := false
+			for _, known := range jobStatuses {
+				valid = valid || st == string(known)
+			}
+			if !valid {
+				return nil, fmt.Errorf("'status' inválido: '%s'", st)
+			}
+			placeholders = append(placeholders, q.bind(st))
+			statuses = append(statuses, st)
+		}
+		q.where = append(q.where, "STATUS IN ("+strings.Join(placeholders, ", ")+")")
+		q.matches = append(q.matches, func(job *AsyncJob) bool {
+			for _, st := range statuses {
+				if string(job.Status) == st {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if procedure := strings.TrimSpace(values.Get("procedure")); procedure != "" {
+		// * es comodín; % y _ se buscan literalmente
+		pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`, "*", "%").Replace(strings.ToUpper(procedure))
+		q.where = append(q.where, "UPPER(PROCEDURE_NAME) LIKE "+q.bind(pattern)+` ESCAPE '\'`)
+		parts := strings.Split(strings.ToUpper(procedure), "*")
+		for i := range parts {
+			parts[i] = regexp.QuoteMeta(parts[i])
+		}
+		re := regexp.MustCompile("^(?s)" + strings.Join(parts, ".*") + "$")
+		q.matches = append(q.matches, func(job *AsyncJob) bool { return re.MatchString(strings.ToUpper(job.ProcName)) })
+	}
+	if schema := strings.TrimSpace(values.Get("schema")); schema != "" {
+		schema = strings.ToUpper(schema)
+		q.where = append(q.where, "SCHEMA_NAME = "+q.bind(schema))
+		q.matches = append(q.matches, func(job *AsyncJob) bool { return job.Schema == schema })
+	}
+	for param, column := range map[string]string{
+		"instance":     "INSTANCE_NAME",
+		"submitted_by": "SUBMITTED_BY",
+		"parent_id":    "PARENT_ID",
+	} {
+		if value := strings.TrimSpace(values.Get(param)); value != "" {
+			q.where = append(q.where, column+" = "+q.bind(value))
+			field := jobListFields[param]
+			q.matches = append(q.matches, func(job *AsyncJob) bool { return field(job) == value })
+		}
+	}
+	for _, param := range []string{"from", "to"} {
+		value := strings.TrimSpace(values.Get(param))
+		if value == "" {
+			continue
+		}
+		t, dateOnly, err := parseJobListTime(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' debe ser una fecha (2024-12-16) o fecha y hora RFC3339 (2024-12-16T15:30:00Z)", param)
+		}
+		if param == "from" {
+			q.where = append(q.where, "START_TIME >= "+q.bind(t))
+			q.matches = append(q.matches, func(job *AsyncJob) bool { return !job.StartTime.Before(t) })
+			continue
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1) // Incluye el día completo
+		}
+		q.where = append(q.where, "START_TIME < "+q.bind(t))
+		q.matches = append(q.matches, func(job *AsyncJob) bool { return job.StartTime.Before(t) })
+	}
+
+	if sort := strings.ToLower(strings.TrimSpace(values.Get("sort"))); sort != "" {
+		if _, ok := jobSortColumns[sort]; !ok {
+			return nil, fmt.Errorf("'sort' debe ser start_time, priority, procedure_name o status")
+		}
+		q.sort = sort
+	}
+	switch strings.ToLower(strings.TrimSpace(values.Get("order"))) {
+	case "", "desc":
+	case "asc":
+		q.desc = false
+	default:
+		return nil, fmt.Errorf("'order' debe ser asc o desc")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil {
+			return nil, fmt.Errorf("'limit' debe ser un número")
+		}
+		if n > 0 {
+			q.limit = n
+		}
+		if q.limit > jobListMaxLimit {
+			q.limit = jobListMaxLimit
+		}
+	}
+	if cursor := values.Get("cursor"); cursor != "" {
+		c, err := decodeJobCursor(cursor)
+		if err != nil || c.Sort != q.sort || c.Desc != q.desc {
+			return nil, fmt.Errorf("'cursor' inválido o de otro orden: usa el next_cursor de la página anterior con los mismos sort y order")
+		}
+		q.cursor = c
+	}
+	switch strings.ToLower(values.Get("summary")) {
+	case "", "0", "false":
+	case "1", "true":
+		q.summary = true
+	default:
+		return nil, fmt.Errorf("'summary' debe ser true o false")
+	}
+	return q, nil
+}
+
+// parseJobListTime acepta una fecha (hora local del servidor) o fecha y hora RFC3339
+func parseJobListTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
+// encodeJobCursor arma el cursor que continúa después del job indicado
+func (q *jobListQuery) encodeJobCursor(job *AsyncJob) string {
+	c := jobCursor{Sort: q.sort, Desc: q.desc, ID: job.ID}
+	switch q.sort {
+	case "start_time":
+		c.Value = job.StartTime.Format(time.RFC3339Nano)
+	case "priority":
+		c.Value = q.sortValue(job)
+	case "procedure_name":
+		c.Value = job.ProcName
+	case "status":
+		c.Value = string(job.Status)
+	}
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeJobCursor lee un cursor de encodeJobCursor y convierte su valor al tipo del campo de orden
+func decodeJobCursor(value string) (*jobCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var c jobCursor
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, err
+	}
+	if c.ID == "" {
+		return nil, fmt.Errorf("cursor sin ID")
+	}
+	switch c.Sort {
+	case "start_time":
+		s, _ := c.Value.(string)
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		c.Value = t
+	case "priority":
+		n, ok := c.Value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("prioridad inválida")
+		}
+		c.Value = int(n)
+	case "procedure_name", "status":
+		if _, ok := c.Value.(string); !ok {
+			return nil, fmt.Errorf("valor inválido")
+		}
+	default:
+		return nil, fmt.Errorf("orden desconocido")
+	}
+	return &c, nil
+}
+
+// run ejecuta la consulta en ASYNC_JOBS: retorna la página pedida, si hay más jobs y el
+// total de jobs que cumplen los filtros
+func (q *jobListQuery) run() (jobs []*AsyncJob, hasMore bool, total int, err error) {
+	filter := "1 = 1"
+	if len(q.where) > 0 {
+		filter = strings.Join(q.where, " AND ")
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM ASYNC_JOBS WHERE "+filter, q.args...).Scan(&total); err != nil {
+		return nil, false, 0, err
+	}
+
+	// Paginación por cursor: los jobs que siguen al último de la página anterior (JOB_ID desempata)
+	column := jobSortColumns[q.sort]
+	op, direction := ">", "ASC"
+	if q.desc {
+		op, direction = "<", "DESC"
+	}
+	where := filter
+	if q.cursor != nil {
+		where += fmt.Sprintf(" AND (%s %s %s OR (%s = %s AND JOB_ID %s %s))", column, op, q.bind(q.cursor.Value),
+			column, q.bind(q.cursor.Value), op, q.bind(q.cursor.ID))
+	}
+
+	// ROWNUM (compatible con 11g); se pide un job extra para saber si hay más
+	rows, err := db.Query(fmt.Sprintf(
+		"SELECT %s FROM (SELECT * FROM ASYNC_JOBS WHERE %s ORDER BY %s %s, JOB_ID %s) WHERE ROWNUM <= %d",
+		jobColumns, where, column, direction, direction, q.limit+1), q.args...)
+	if err != nil {
+		return nil, false, 0, err
+	}
+	defer rows.Close()
+	jobs = []*AsyncJob{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, false, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, 0, err
+	}
+	if len(jobs) > q.limit {
+		jobs, hasMore = jobs[:q.limit], true
+	}
+	return jobs, hasMore, total, nil
+}
+
+// sortValue retorna el valor del campo de orden de un job, como lo compara Oracle
+func (q *jobListQuery) sortValue(job *AsyncJob) interface{} {
+	switch q.sort {
+	case "priority":
+		if job.Priority == 0 {
+			return defaultJobPriority // NVL(PRIORITY, 5)
+		}
+		return job.Priority
+	case "procedure_name":
+		return job.ProcName
+	case "status":
+		return string(job.Status)
+	}
+	return job.StartTime
+}
+
+// compareJobSort compara dos valores del campo de orden y, si son iguales, los IDs
+func compareJobSort(a interface{}, aID string, b interface{}, bID string) int {
+	c := 0
+	switch av := a.(type) {
+	case time.Time:
+		c = av.Compare(b.(time.Time))
+	case int:
+		c = av - b.(int)
+	case string:
+		c = strings.Compare(av, b.(string))
+	}
+	if c == 0 {
+		c = strings.Compare(aID, bID)
+	}
+	return c
+}
+
+// runInMemory aplica la consulta a los jobs en memoria; se usa si ASYNC_JOBS no se puede consultar.
+// Solo incluye los jobs de esta instancia y los del último día cargados al iniciar.
+func (q *jobListQuery) runInMemory() (jobs []*AsyncJob, hasMore bool, total int) {
+	jobs = []*AsyncJob{}
+	for _, current := range jobManager.GetAllJobs() {
+		snap := current.snapshotLocked()
+		matches := true
+		for _, match := range q.matches {
+			matches = matches && match(&snap)
+		}
+		if matches {
+			jobs = append(jobs, &snap)
+		}
+	}
+	total = len(jobs)
+
+	sign := 1
+	if q.desc {
+		sign = -1
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return sign*compareJobSort(q.sortValue(jobs[i]), jobs[i].ID, q.sortValue(jobs[j]), jobs[j].ID) < 0
+	})
+	if q.cursor != nil {
+		after := jobs[:0]
+		for _, job := range jobs {
+			if sign*compareJobSort(q.sortValue(job), job.ID, q.cursor.Value, q.cursor.ID) > 0 {
+				after = append(after, job)
+			}
+		}
+		jobs = after
+	}
+	if len(jobs) > q.limit {
+		jobs, hasMore = jobs[:q.limit], true
+	}
+	return jobs, hasMore, total
+}
+
+// listJobsHandler responde GET /jobs con los jobs de ASYNC_JOBS (incluido el historial que
+// ya no está en memoria) filtrados, ordenados y paginados
+func listJobsHandler(w http.ResponseWriter, r *http.Request) {
+	q, err := parseJobListQuery(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	source := "database"
+	jobs, hasMore, total, err := q.run()
+	if err != nil {
+		// Sin ASYNC_JOBS se responde con los jobs en memoria, con los mismos filtros
+		log.Printf("[JOBS] Error consultando ASYNC_JOBS, se listan los jobs en memoria: %v", err)
+		source = "memory"
+		jobs, hasMore, total = q.runInMemory()
+	}
+
+	resp := map[string]interface{}{
+		"total":    total,
+		"count":    len(jobs),
+		"jobs":     jobs,
+		"has_more": hasMore,
+		"source":   source,
+		"queue":    jobQueue.Stats(),
+	}
+	if hasMore {
+		resp["next_cursor"] = q.encodeJobCursor(jobs[len(jobs)-1])
+	}
+
+	for i, job := range jobs {
+		// Los jobs de esta instancia se guardan en la BD en segundo plano: memoria tiene el último estado
+		if current, exists := jobManager.GetJob(job.ID); exists {
+			if snap := current.snapshotLocked(); jobRecovery.owns(&snap) {
+				jobs[i] = &snap
+			}
+		}
+		if q.summary {
+			jobs[i].Params = nil
+			jobs[i].Result = nil
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestJobCursorRoundTrip(t *testing.T) {
+	start := time.Date(2024, 12, 16, 15, 30, 0, 123456789, time.UTC)
+	job := &AsyncJob{ID: "abc", StartTime: start, ProcName: "PKG.PROC", Status: JobStatusRunning}
+	tests := map[string]interface{}{
+		"start_time":     start,
+		"priority":       defaultJobPriority, // Sin prioridad vale como NVL(PRIORITY, 5)
+		"procedure_name": "PKG.PROC",
+		"status":         "running",
+	}
+	for sort, want := range tests {
+		for _, desc := range []bool{true, false} {
+			q := &jobListQuery{sort: sort, desc: desc}
+			c, err := decodeJobCursor(q.encodeJobCursor(job))
+			if err != nil {
+				t.Fatalf("%s: %v", sort, err)
+			}
+			if c.Sort != sort || c.Desc != desc || c.ID != "abc" {
+				t.Errorf("%s: cursor = %+v", sort, c)
+			}
+			if got, ok := c.Value.(time.Time); ok {
+				if !got.Equal(want.(time.Time)) {
+					t.Errorf("%s: valor = %v, se esperaba %v", sort, got, want)
+				}
+			} else if c.Value != want {
+				t.Errorf("%s: valor = %#v, se esperaba %#v", sort, c.Value, want)
+			}
+		}
+	}
+}
+
+func TestDecodeJobCursorErrors(t *testing.T) {
+	for _, raw := range []string{
+		"no-es-base64!",
+		"bm8tanNvbg",                          // "no-json"
+		"eyJzIjoic3RhcnRfdGltZSIsInYiOiJ4In0", // {"s":"start_time","v":"x"} sin ID
+		"eyJzIjoic3RhcnRfdGltZSIsInYiOiJ4IiwiaWQiOiJhIn0",  // fecha inválida
+		"eyJzIjoicHJpb3JpdHkiLCJ2IjoiYWx0YSIsImlkIjoiYSJ9", // {"s":"priority","v":"alta","id":"a"}
+		"eyJzIjoib3RybyIsInYiOjEsImlkIjoiYSJ9",             // {"s":"otro","v":1,"id":"a"}
+	} {
+		if c, err := decodeJobCursor(raw); err == nil {
+			t.Errorf("decodeJobCursor(%q) = %+v; se esperaba error", raw, c)
+		}
+	}
+}
+
+func TestParseJobListQuery(t *testing.T) {
+	q, err := parseJobListQuery(url.Values{
+		"status":    {"running, pending"},
+		"procedure": {"pkg_*.proc%"},
+		"sort":      {"Priority"},
+		"order":     {"asc"},
+		"limit":     {"5000"},
+		"summary":   {"true"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.sort != "priority" || q.desc || q.limit != jobListMaxLimit || !q.summary {
+		t.Errorf("consulta = %+v", q)
+	}
+	wantWhere := []string{"STATUS IN (:1, :2)", `UPPER(PROCEDURE_NAME) LIKE :3 ESCAPE '\'`}
+	if !reflect.DeepEqual(q.where, wantWhere) {
+		t.Errorf("where = %q, se esperaba %q", q.where, wantWhere)
+	}
+	if q.args[2] != `PKG\_%.PROC\%` {
+		t.Errorf("patrón = %v", q.args[2])
+	}
+
+	// Un cursor de otro orden no sirve
+	cursor := (&jobListQuery{sort: "start_time", desc: true}).encodeJobCursor(&AsyncJob{ID: "a", StartTime: time.Now()})
+	for _, values := range []url.Values{
+		{"status": {"terminado"}},
+		{"sort": {"job_id"}},
+		{"order": {"arriba"}},
+		{"limit": {"diez"}},
+		{"summary": {"si"}},
+		{"from": {"ayer"}},
+		{"cursor": {cursor}, "order": {"asc"}},
+	} {
+		if _, err := parseJobListQuery(values); err == nil {
+			t.Errorf("parseJobListQuery(%v): se esperaba error", values)
+		}
+	}
+}
+
+func TestRunInMemory(t *testing.T) {
+	base := time.Date(2024, 12, 16, 10, 0, 0, 0, time.UTC)
+	jobs := map[string]*AsyncJob{}
+	for i, spec := range []struct {
+		id     string
+		proc   string
+		status JobStatus
+	}{
+		{"a", "PKG_VENTAS.CERRAR", JobStatusCompleted},
+		{"b", "PKG_VENTAS.ABRIR", JobStatusRunning},
+		{"c", "PKG_STOCK.CERRAR", JobStatusCompleted},
+		{"d", "PKG_VENTAS.CERRAR", JobStatusFailed},
+		{"e", "PKG_VENTAS.CERRAR", JobStatusCompleted},
+	} {
+		jobs[spec.id] = &AsyncJob{ID: spec.id, ProcName: spec.proc, Status: spec.status, StartTime: base.Add(time.Duration(i) * time.Minute)}
+	}
+	jobManager.mu.Lock()
+	saved := jobManager.jobs
+	jobManager.jobs = jobs
+	jobManager.mu.Unlock()
+	defer func() {
+		jobManager.mu.Lock()
+		jobManager.jobs = saved
+		jobManager.mu.Unlock()
+	}()
+
+	ids := func(jobs []*AsyncJob) []string {
+		out := []string{}
+		for _, job := range jobs {
+			out = append(out, job.ID)
+		}
+		return out
+	}
+
+	q, err := parseJobListQuery(url.Values{"procedure": {"pkg_ventas.*"}, "status": {"completed,failed"}, "limit": {"2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, hasMore, total := q.runInMemory()
+	if got := ids(page); !reflect.DeepEqual(got, []string{"e", "d"}) || !hasMore || total != 3 {
+		t.Fatalf("primera página = %v, %v, %d", got, hasMore, total)
+	}
+
+	// La página siguiente continúa después del cursor
+	q, err = parseJobListQuery(url.Values{"procedure": {"pkg_ventas.*"}, "status": {"completed,failed"}, "limit": {"2"},
+		"cursor": {q.encodeJobCursor(page[1])}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, hasMore, _ = q.runInMemory()
+	if got := ids(page); !reflect.DeepEqual(got, []string{"a"}) || hasMore {
+		t.Errorf("segunda página = %v, %v", got, hasMore)
+	}
+
+	// Orden por nombre, desempatando por ID
+	q, err = parseJobListQuery(url.Values{"sort": {"procedure_name"}, "order": {"asc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _, _ = q.runInMemory()
+	if got := ids(page); !reflect.DeepEqual(got, []string{"c", "b", "a", "d", "e"}) {
+		t.Errorf("orden por nombre = %v", got)
+	}
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Valores por defecto del pool de workers de jobs asíncronos
+const (
+	defaultAsyncWorkers       = 5
+	defaultAsyncQueueSize     = 100
+	defaultAsyncMaxPerProgram = 0  // 0 = sin límite por procedimiento
+	asyncRetryAfterSeconds    = 30 // Retry-After sugerido cuando la cola está llena
+	defaultAsyncPriorityAging = time.Minute
+)
+
+// Rango de prioridades de los jobs (mayor número = se ejecuta antes)
+const (
+	minJobPriority     = 1
+	maxJobPriority     = 10
+	defaultJobPriority = 5
+)
+
+// asyncProcedureRequest es el cuerpo de /procedure/async: el de /procedure más las opciones del job
+type asyncProcedureRequest struct {
+	ProcedureRequest
+	Priority    int          `json:"priority,omitempty"`     // 1 (baja) a 10 (alta); por defecto 5
+	CallbackURL string       `json:"callback_url,omitempty"` // Recibe el job final al terminar (ver webhook.go)
+	Retry       *RetryPolicy `json:"retry,omitempty"`        // Reintentos ante errores transitorios (ver retry.go)
+	OnInterrupt string       `json:"on_interrupt,omitempty"` // fail (por defecto) o requeue (ver orphans.go)
+}
+
+// jobOptions son las opciones con que se crea un job
+type jobOptions struct {
+	Priority    int
+	CallbackURL string
+	Retry       *RetryPolicy
+	Workflow    *WorkflowState // Job padre de un workflow (no se encola, ver workflow.go)
+	ParentID    string         // Workflow al que pertenece el job
+	Step        string         // Paso del workflow que ejecuta
+	OnInterrupt string         // fail o requeue si la instancia se detiene durante la ejecución
+	Unclaimed   bool           // Queda en la cola compartida sin instancia dueña (ver cluster.go)
+	Schema      string         // Esquema del procedimiento
+	SubmittedBy string         // Quién creó el job: IP del cliente o schedule:<id>
+}
+
+// jobPriority valida la prioridad pedida (0 = no indicada)
+func jobPriority(priority int) (int, error) {
+	if priority == 0 {
+		return defaultJobPriority, nil
+	}
+	if priority < minJobPriority || priority > maxJobPriority {
+		return 0, fmt.Errorf("'priority' debe estar entre %d y %d", minJobPriority, maxJobPriority)
+	}
+	return priority, nil
+}
+
+// errQueueFull se retorna cuando la cola de jobs no admite más pendientes
+var errQueueFull = fmt.Errorf("cola de jobs llena")
+
+// queuedJob es un job pendiente en la cola; run se ejecuta en un worker con un contexto
+// que se cancela con POST /jobs/{id}/cancel
+type queuedJob struct {
+	id       string
+	program  string // Clave del procedimiento para el límite por procedimiento
+	priority int
+	queuedAt time.Time // Creación del job; la espera suma prioridad (aging)
+	run      func(ctx context.Context)
+}
+
+// JobQueue reparte los jobs pendientes de esta instancia entre un número fijo de workers,
+// respetando el límite de ejecuciones simultáneas por procedimiento. Los jobs se persisten en
+// ASYNC_JOBS con estado pending; con varias instancias los que ninguna tomó esperan en la
+// tabla hasta que una tenga workers libres (ver cluster.go).
+type JobQueue struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	pending  []*queuedJob
+	running  map[string]int                // Ejecuciones en curso por procedimiento
+	cancels  map[string]context.CancelFunc // Cancelación de los jobs en ejecución, por ID
+	workers  int
+	capacity int
+	perProg  int                    // Límite por procedimiento por defecto (0 = sin límite)
+	limits   map[string]int         // Límites específicos (ASYNC_PROCEDURE_LIMITS)
+	aging    time.Duration          // Espera que suma un punto de prioridad (0 = sin aging)
+	delayed  map[string]*time.Timer // Reintentos esperando su backoff, por ID
+}
+
+var jobQueue *JobQueue
+
+// newJobQueueFromEnv arma la cola con ASYNC_WORKERS, ASYNC_QUEUE_SIZE, ASYNC_MAX_PER_PROCEDURE
+// y ASYNC_PROCEDURE_LIMITS (lista PROGRAMA:N separada por comas)
+func newJobQueueFromEnv() (*JobQueue, error) {
+	q := &JobQueue{
+		running:  make(map[string]int),
+		cancels:  make(map[string]context.CancelFunc),
+		workers:  defaultAsyncWorkers,
+		capacity: defaultAsyncQueueSize,
+		perProg:  defaultAsyncMaxPerProgram,
+		limits:   make(map[string]int),
+		aging:    defaultAsyncPriorityAging,
+		delayed:  make(map[string]*time.Timer),
+	}
+	q.cond = sync.NewCond(&q.mu)
+
+	intEnv := func(name string, min int, dest *int) error {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			return nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < min {
+			return fmt.Errorf("%s debe ser un número entero mayor o igual a %d", name, min)
+		}
+		*dest = n
+		return nil
+	}
+	if err := intEnv("ASYNC_WORKERS", 1, &q.workers); err != nil {
+		return nil, err
+	}
+	if err := intEnv("ASYNC_QUEUE_SIZE", 1, &q.capacity); err != nil {
+		return nil, err
+	}
+	if err := intEnv("ASYNC_MAX_PER_PROCEDURE", 0, &q.perProg); err != nil {
+		return nil, err
+	}
+	if value := strings.TrimSpace(os.Getenv("ASYNC_PRIORITY_AGING")); value != "" {
+		aging, err := time.ParseDuration(value)
+		if value == "0" {
+			aging, err = 0, nil
+		}
+		if err != nil || aging < 0 {
+			return nil, fmt.Errorf("ASYNC_PRIORITY_AGING debe ser una duración (p.ej. 1m, 30s) o 0")
+		}
+		q.aging = aging
+	}
+	for _, item := range strings.Split(os.Getenv("ASYNC_PROCEDURE_LIMITS"), ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		idx := strings.LastIndex(item, ":")
+		n := -1
+		if idx > 0 {
+			n, _ = strconv.Atoi(strings.TrimSpace(item[idx+1:]))
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("ASYNC_PROCEDURE_LIMITS: entrada inválida '%s' (formato PROGRAMA:N)", item)
+		}
+		q.limits[strings.ToUpper(strings.TrimSpace(item[:idx]))] = n
+	}
+	return q, nil
+}
+
+// programKey es el nombre completo en mayúsculas de un programa (ESQUEMA.PAQUETE.NOMBRE o el indicado)
+func programKey(schema, name string) string {
+	key := strings.ToUpper(name)
+	if schema != "" {
+		key = strings.ToUpper(schema) + "." + key
+	}
+	return key
+}
+
+// programMatches indica si la clave de un programa corresponde a un nombre configurado,
+// que puede omitir el esquema o el paquete del principio (p.ej. PKG.PROC coincide con ESQ.PKG.PROC)
+func programMatches(key, configured string) bool {
+	return key == configured || strings.HasSuffix(key, "."+configured)
+}
+
+// limitFor retorna el máximo de ejecuciones simultáneas de un programa (0 = sin límite)
+func (q *JobQueue) limitFor(program string) int {
+	for name, n := range q.limits {
+		if programMatches(program, name) {
+			return n
+		}
+	}
+	return q.perProg
+}
+
+// Start lanza los workers
+func (q *JobQueue) Start() {
+	for i := 0; i < q.workers; i++ {
+		go q.worker()
+	}
+	log.Printf("[JOBS] Pool iniciado: %d workers, cola de %d, máximo por procedimiento %d (0 = sin límite), aging %v",
+		q.workers, q.capacity, q.perProg, q.aging)
+}
+
+// Full indica si la cola ya no admite jobs; cuenta también los pendientes de la cola compartida
+func (q *JobQueue) Full() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.pending)+jobCluster.Backlog() >= q.capacity
+}
+
+// IdleSlots retorna cuántos jobs más puede empezar esta instancia sin que esperen en su cola
+func (q *JobQueue) IdleSlots() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	busy := len(q.pending)
+	for _, n := range q.running {
+		busy += n
+	}
+	if busy >= q.workers {
+		return 0
+	}
+	return q.workers - busy
+}
+
+// Enqueue agrega un job al final de la cola; falla con errQueueFull si no hay lugar
+func (q *JobQueue) Enqueue(job *queuedJob) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) >= q.capacity {
+		return errQueueFull
+	}
+	q.pending = append(q.pending, job)
+	q.cond.Broadcast()
+	return nil
+}
+
+// EnqueueAfter agrega el job a la cola después de delay (reintentos). Como el job ya fue
+// admitido no se le aplica la capacidad de la cola.
+func (q *JobQueue) EnqueueAfter(job *queuedJob, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.delayed[job.id] = time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if _, ok := q.delayed[job.id]; !ok {
+			return // Cancelado durante la espera
+		}
+		delete(q.delayed, job.id)
+		q.pending = append(q.pending, job)
+		q.cond.Broadcast()
+	})
+}
+
+// effectivePriority es la prioridad del job más un punto por cada intervalo de aging esperado,
+// para que los jobs de baja prioridad no esperen indefinidamente
+func (q *JobQueue) effectivePriority(job *queuedJob, now time.Time) int {
+	if q.aging <= 0 {
+		return job.priority
+	}
+	return job.priority + int(now.Sub(job.queuedAt)/q.aging)
+}
+
+// next espera hasta que haya un job pendiente cuyo procedimiento no esté en su límite, retira el de
+// mayor prioridad efectiva (a igual prioridad, el más antiguo) y registra su cancelación
+func (q *JobQueue) next() (*queuedJob, context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		now := time.Now()
+		best, bestPriority := -1, 0
+		for i, job := range q.pending {
+			if limit := q.limitFor(job.program); limit > 0 && q.running[job.program] >= limit {
+				continue
+			}
+			p := q.effectivePriority(job, now)
+			if best == -1 || p > bestPriority || (p == bestPriority && job.queuedAt.Before(q.pending[best].queuedAt)) {
+				best, bestPriority = i, p
+			}
+		}
+		if best >= 0 {
+			job := q.pending[best]
+			q.pending = append(q.pending[:best], q.pending[best+1:]...)
+			q.running[job.program]++
+			ctx, cancel := context.WithCancel(context.Background())
+			q.cancels[job.id] = cancel
+			return job, ctx
+		}
+		q.cond.Wait()
+	}
+}
+
+// done libera el lugar del procedimiento y despierta a los workers en espera
+func (q *JobQueue) done(job *queuedJob) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cancel, ok := q.cancels[job.id]; ok {
+		cancel()
+		delete(q.cancels, job.id)
+	}
+	q.running[job.program]--
+	if q.running[job.program] <= 0 {
+		delete(q.running, job.program)
+	}
+	q.cond.Broadcast()
+}
+
+func (q *JobQueue) worker() {
+	for {
+		job, ctx := q.next()
+		job.run(ctx)
+		q.done(job)
+		jobCluster.Wake() // Worker libre: buscar jobs en la cola compartida
+	}
+}
+
+// Cancel quita el job de la cola si está pendiente o cancela su contexto si está en ejecución.
+// Retorna si estaba pendiente y si se encontró.
+func (q *JobQueue) Cancel(id string) (wasPending bool, found bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.remove(id) {
+		return true, true
+	}
+	if cancel, ok := q.cancels[id]; ok {
+		cancel()
+		return false, true
+	}
+	return false, false
+}
+
+// Remove quita el job de la cola si todavía no empezó (pendiente o esperando un reintento)
+func (q *JobQueue) Remove(id string) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.remove(id)
+}
+
+func (q *JobQueue) remove(id string) bool {
+	for i, job := range q.pending {
+		if job.id == id {
+			q.pending = append(q.pending[:i], q.pending[i+1:]...)
+			return true
+		}
+	}
+	if timer, ok := q.delayed[id]; ok {
+		timer.Stop()
+		delete(q.delayed, id)
+		return true
+	}
+	return false
+}
+
+// Stats retorna la cantidad de jobs pendientes y en ejecución
+func (q *JobQueue) Stats() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	running := 0
+	for _, n := range q.running {
+		running += n
+	}
+	return map[string]interface{}{
+		"pending":   len(q.pending),
+		"delayed":   len(q.delayed),
+		"running":   running,
+		"workers":   q.workers,
+		"capacity":  q.capacity,
+		"unclaimed": jobCluster.Backlog(), // Pendientes que todavía no tomó ninguna instancia
+	}
+}
+
+// procedureQueuedJob arma la entrada de la cola que ejecuta el procedimiento de un job
+func procedureQueuedJob(job *AsyncJob, req ProcedureRequest) *queuedJob {
+	jobID := job.ID
+	return &queuedJob{
+		id:       jobID,
+		program:  programKey(req.Schema, req.Name),
+		priority: job.Priority,
+		queuedAt: job.StartTime,
+		run:      func(ctx context.Context) { runProcedureJob(ctx, jobID, req) },
+	}
+}
+
+// enqueueProcedureJob encola la ejecución del procedimiento de un job
+func enqueueProcedureJob(job *AsyncJob, req ProcedureRequest) error {
+	return jobQueue.Enqueue(procedureQueuedJob(job, req))
+}
+
+// submitProcedureJob crea el job de un procedimiento ya preparado y lo encola. Si esta instancia
+// no tiene workers libres y la cola es compartida, el job queda en ASYNC_JOBS para la primera
+// instancia que se libere. Si no se puede encolar el job queda failed y se retorna el error
+// (errQueueFull si la cola está llena).
+func submitProcedureJob(req ProcedureRequest, params map[string]interface{}, opts jobOptions) (*AsyncJob, error) {
+	opts.Schema = req.Schema
+	return submitJob(req.Name, params, opts, func(job *AsyncJob) *queuedJob {
+		return procedureQueuedJob(job, req)
+	})
+}
+
+// submitJob crea un job y encola la entrada que arma entry (ver submitProcedureJob)
+func submitJob(name string, params map[string]interface{}, opts jobOptions, entry func(*AsyncJob) *queuedJob) (*AsyncJob, error) {
+	opts.Unclaimed = jobCluster.shared() && jobQueue.IdleSlots() == 0
+	job := jobManager.CreateJob(name, params, opts)
+	if opts.Unclaimed {
+		jobCluster.backlog.Add(1)
+		return job, nil
+	}
+	if err := jobQueue.Enqueue(entry(job)); err != nil {
+		endTime := time.Now()
+		jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
+			j.Status = JobStatusFailed
+			j.Error = "No se pudo encolar: " + err.Error()
+			j.EndTime = &endTime
+			j.Progress = 100
+		})
+		return job, err
+	}
+	return job, nil
+}
+
+// procedureRequestFromParams reconstruye la llamada desde los parámetros guardados de un job
+// (ver procedureRequestToParams)
+func procedureRequestFromParams(params map[string]interface{}) (ProcedureRequest, error) {
+	var req ProcedureRequest
+	raw, err := json.Marshal(params)
+	if err == nil {
+		err = json.Unmarshal(raw, &req)
+	}
+	return req, err
+}
+
+// requeuePendingJobs vuelve a encolar los jobs que quedaron pendientes en ASYNC_JOBS
+// (p.ej. al reiniciar la API), en orden de creación
+func requeuePendingJobs() {
+	jobs := jobManager.GetAllJobs()
+	pending := []*AsyncJob{}
+	for _, job := range jobs {
+		// Los workflows no se ejecutan en la cola: avanzan con sus pasos (resumeWorkflows).
+		// Los jobs de otras instancias activas los ejecuta su dueño.
+		if job.Status == JobStatusPending && job.Workflow == nil && jobRecovery.owns(job) {
+			pending = append(pending, job)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].StartTime.Before(pending[j].StartTime) })
+
+	count := 0
+	for _, job := range pending {
+		if enqueueStoredJob(job) {
+			count++
+		}
+	}
+	if count > 0 {
+		log.Printf("[JOBS] %d jobs pendientes reencolados", count)
+	}
+}
+
+// enqueueStoredJob encola un job pendiente leído de ASYNC_JOBS (respetando la espera de su
+// próximo reintento). Si no se puede, el job queda failed.
+func enqueueStoredJob(job *AsyncJob) bool {
+	snap := job.snapshotLocked()
+	var err error
+	if query, ok := queryJobSQL(&snap); ok {
+		// Consulta de /query/async (ver queryjobs.go)
+		err = jobQueue.Enqueue(queryQueuedJob(&snap, query))
+	} else if req, perr := procedureRequestFromParams(snap.Params); perr != nil {
+		err = perr
+	} else if snap.NextRetry != nil && snap.NextRetry.After(time.Now()) {
+		// Reintento esperando su backoff
+		jobQueue.EnqueueAfter(procedureQueuedJob(&snap, req), time.Until(*snap.NextRetry))
+	} else {
+		err = enqueueProcedureJob(&snap, req)
+	}
+	if err != nil {
+		endTime := time.Now()
+		jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
+			j.Status = JobStatusFailed
+			j.Error = fmt.Sprintf("No se pudo reencolar el job: %v", err)
+			j.EndTime = &endTime
+			j.Progress = 100
+		})
+		return false
+	}
+	return true
+}
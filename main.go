@@ -29,7 +29,7 @@ var db *sql.DB
 var logFileName string  // Nombre del log de la instancia
 var instanceName string // Nombre/etiqueta de la instancia
 
-// JobStatus representa el estado de un job as├¡ncrono
+// JobStatus representa el estado de un job asíncrono
 type JobStatus string
 
 const (
@@ -37,9 +37,29 @@ const (
 	JobStatusRunning   JobStatus = "running"
 	JobStatusCompleted JobStatus = "completed"
 	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+	// La instancia que lo ejecutaba se detuvo (ver orphans.go)
+	JobStatusInterrupted JobStatus = "interrupted"
 )
 
-// AsyncJob representa un job de procedimiento en ejecuci├│n
+// jobStatuses son los estados válidos de un job (CHECK de ASYNC_JOBS.STATUS)
+var jobStatuses = []JobStatus{JobStatusPending, JobStatusRunning, JobStatusCompleted, JobStatusFailed, JobStatusCancelled, JobStatusInterrupted}
+
+// jobStatusCheck retorna la condición del CHECK de ASYNC_JOBS.STATUS
+func jobStatusCheck() string {
+	values := make([]string, len(jobStatuses))
+	for i, st := range jobStatuses {
+		values[i] = "'" + string(st) + "'"
+	}
+	return "STATUS IN (" + strings.Join(values, ", ") + ")"
+}
+
+// isFinal indica si el job ya terminó (completed, failed, cancelled o interrupted)
+func (st JobStatus) isFinal() bool {
+	return st == JobStatusCompleted || st == JobStatusFailed || st == JobStatusCancelled || st == JobStatusInterrupted
+}
+
+// AsyncJob representa un job de procedimiento en ejecución
 type AsyncJob struct {
 	ID        string                 `json:"id"`
 	Status    JobStatus              `json:"status"`
@@ -51,6 +71,28 @@ type AsyncJob struct {
 	Result    map[string]interface{} `json:"result,omitempty"`
 	Error     string                 `json:"error,omitempty"`
 	Progress  int                    `json:"progress"` // 0-100
+	Priority  int                    `json:"priority"` // 1 (baja) a 10 (alta)
+
+	ProgressMessage string `json:"progress_message,omitempty"` // Mensaje de avance informado por el procedimiento (ver progress.go)
+
+	Schema      string `json:"schema,omitempty"`       // Esquema del procedimiento (vacío = el del usuario)
+	SubmittedBy string `json:"submitted_by,omitempty"` // IP del cliente que lo creó o schedule:<id>
+
+	CallbackURL string        `json:"callback_url,omitempty"` // Recibe el job final (ver webhook.go)
+	Webhook     *WebhookState `json:"webhook,omitempty"`
+
+	Attempt   int          `json:"attempt,omitempty"`    // Intento en curso o último ejecutado (1 = primero)
+	Retry     *RetryPolicy `json:"retry,omitempty"`      // Política de reintentos (ver retry.go)
+	Attempts  []JobAttempt `json:"attempts,omitempty"`   // Intentos fallidos
+	NextRetry *time.Time   `json:"next_retry,omitempty"` // Próximo reintento mientras espera su backoff
+
+	ParentID string         `json:"parent_id,omitempty"` // Workflow al que pertenece (ver workflow.go)
+	Step     string         `json:"step,omitempty"`      // Paso del workflow que ejecuta
+	Workflow *WorkflowState `json:"workflow,omitempty"`  // Estado de los pasos, en el job padre
+
+	Instance    string     `json:"instance,omitempty"`     // Instancia dueña del job (ver orphans.go)
+	Heartbeat   *time.Time `json:"heartbeat,omitempty"`    // Última señal de vida de la instancia dueña
+	OnInterrupt string     `json:"on_interrupt,omitempty"` // fail o requeue si la instancia se detiene durante la ejecución
 }
 
 // QueryLog representa un registro de consulta ejecutada
@@ -67,17 +109,23 @@ type QueryLog struct {
 	UserIP        string    `json:"user_ip,omitempty"`
 }
 
-// JobManager gestiona los jobs as├¡ncronos
+// JobManager gestiona los jobs asíncronos
 type JobManager struct {
 	jobs map[string]*AsyncJob
+	subs map[string]map[chan AsyncJob]struct{} // Suscriptores a cambios de cada job (ver jobevents.go)
 	mu   sync.RWMutex
+
+	writes   map[string]*AsyncJob // Último estado pendiente de guardar de cada job (ver persistJob)
+	writesMu sync.Mutex
 }
 
 var jobManager = &JobManager{
-	jobs: make(map[string]*AsyncJob),
+	jobs:   make(map[string]*AsyncJob),
+	subs:   make(map[string]map[chan AsyncJob]struct{}),
+	writes: make(map[string]*AsyncJob),
 }
 
-// generateJobID genera un ID ├║nico para el job
+// generateJobID genera un ID único para el job
 func generateJobID() string {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
@@ -89,22 +137,35 @@ func generateJobID() string {
 }
 
 // CreateJob crea un nuevo job y lo registra (en memoria y BD)
-func (jm *JobManager) CreateJob(procName string, params map[string]interface{}) *AsyncJob {
-	jm.mu.Lock()
-	defer jm.mu.Unlock()
-
+func (jm *JobManager) CreateJob(procName string, params map[string]interface{}, opts jobOptions) *AsyncJob {
 	job := &AsyncJob{
-		ID:        generateJobID(),
-		Status:    JobStatusPending,
-		ProcName:  procName,
-		Params:    params,
-		StartTime: time.Now(),
-		Progress:  0,
+		ID:          generateJobID(),
+		Status:      JobStatusPending,
+		ProcName:    procName,
+		Params:      params,
+		StartTime:   time.Now(),
+		Progress:    0,
+		Priority:    opts.Priority,
+		CallbackURL: opts.CallbackURL,
+		Retry:       opts.Retry,
+		ParentID:    opts.ParentID,
+		Step:        opts.Step,
+		Workflow:    opts.Workflow,
+		OnInterrupt: opts.OnInterrupt,
+		Schema:      strings.ToUpper(opts.Schema),
+		SubmittedBy: opts.SubmittedBy,
+	}
+	if !opts.Unclaimed {
+		now := job.StartTime
+		job.Instance = jobRecovery.owner
+		job.Heartbeat = &now
 	}
+	jm.mu.Lock()
 	jm.jobs[job.ID] = job
+	jm.mu.Unlock()
 
-	// Guardar en base de datos
-	go jm.saveJobToDB(job)
+	// Guardar en base de datos antes de encolarlo: la cola de pendientes se reconstruye desde ASYNC_JOBS
+	jm.saveJobToDB(job)
 
 	return job
 }
@@ -135,13 +196,53 @@ func (jm *JobManager) UpdateJob(id string, updateFn func(*AsyncJob)) {
 	defer jm.mu.Unlock()
 
 	if job, exists := jm.jobs[id]; exists {
+		wasFinal := job.Status.isFinal()
 		updateFn(job)
-		// Actualizar en base de datos
-		go jm.updateJobInDB(job)
+		// Al terminar, notificar el resultado a callback_url
+		if !wasFinal && job.Status.isFinal() && job.CallbackURL != "" && job.Webhook == nil {
+			payload := webhookPayload(job)
+			job.Webhook = &WebhookState{Status: WebhookPending, Attempts: []WebhookAttempt{}}
+			go webhooks.Deliver(job.ID, job.CallbackURL, payload, 1)
+		}
+		// El fin del paso de un workflow puede habilitar los siguientes
+		if !wasFinal && job.Status.isFinal() && job.ParentID != "" {
+			go workflowManager.stepFinished(job.ParentID)
+		}
+		jm.notify(job)
+		// Actualizar en base de datos con una copia tomada bajo el lock
+		jm.persistJob(job.snapshot())
+	}
+}
+
+// persistJob guarda el estado del job en segundo plano. Las escrituras de un mismo job se hacen
+// de a una y en orden: si llega un estado mientras se guarda otro, queda pendiente y reemplaza
+// a los anteriores que aún no se escribieron, así la BD siempre termina con el último.
+func (jm *JobManager) persistJob(snap AsyncJob) {
+	jm.writesMu.Lock()
+	_, writing := jm.writes[snap.ID]
+	jm.writes[snap.ID] = &snap
+	jm.writesMu.Unlock()
+	if writing {
+		return
 	}
+
+	go func() {
+		for {
+			jm.writesMu.Lock()
+			next := jm.writes[snap.ID]
+			if next == nil {
+				delete(jm.writes, snap.ID)
+				jm.writesMu.Unlock()
+				return
+			}
+			jm.writes[snap.ID] = nil // Escritura en curso, sin estado pendiente
+			jm.writesMu.Unlock()
+			jm.updateJobInDB(next)
+		}
+	}()
 }
 
-// CleanupOldJobs elimina jobs completados hace m├ís de 24 horas
+// CleanupOldJobs elimina jobs completados hace más de 24 horas
 func (jm *JobManager) CleanupOldJobs() {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
@@ -150,15 +251,16 @@ func (jm *JobManager) CleanupOldJobs() {
 	for id, job := range jm.jobs {
 		if job.EndTime != nil && job.EndTime.Before(cutoff) {
 			delete(jm.jobs, id)
+			jm.closeSubscriptions(id)
 		}
 	}
 }
 
-// formatObjectName formatea el nombre de un procedimiento/funci├│n considerando esquema
-// Retorna el nombre formateado seg├║n las reglas:
+// formatObjectName formatea el nombre de un procedimiento/función considerando esquema
+// Retorna el nombre formateado según las reglas:
 // - Si schema especificado: SCHEMA.NAME (sin comillas)
 // - Si name contiene punto: "PARTE1"."PARTE2" (con comillas)
-// - Si nombre simple: NAME (sin comillas, en may├║sculas)
+// - Si nombre simple: NAME (sin comillas, en mayúsculas)
 func formatObjectName(schema, name string) string {
 	if schema != "" {
 		// Si se especifica el esquema por separado, usar SCHEMA.NAME sin comillas
@@ -191,6 +293,102 @@ func parseDateParam(value interface{}) (time.Time, error) {
 	return t, fmt.Errorf("no se pudo parsear fecha")
 }
 
+// parseBoolParam convierte un valor JSON a 1/0 (o nil) para enlazarlo como BOOLEAN
+func parseBoolParam(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		if v {
+			return 1, nil
+		}
+		return 0, nil
+	case float64:
+		if v == 1 {
+			return 1, nil
+		} else if v == 0 {
+			return 0, nil
+		}
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "1", "s", "si", "sí", "y", "yes":
+			return 1, nil
+		case "false", "0", "n", "no":
+			return 0, nil
+		}
+	}
+	return nil, fmt.Errorf("valor booleano inválido: %v", value)
+}
+
+// bindConversion describe cómo convertir un bind cuyo tipo PL/SQL no se puede enlazar directamente
+// (BOOLEAN, INTERVAL): se declara una variable local de tipo Decl y se convierte antes/después de la llamada.
+// In y Out son formatos con %[1]s = expresión origen.
+type bindConversion struct {
+	Decl string
+	In   string
+	Out  string
+}
+
+// boolConversion enlaza BOOLEAN como 1/0
+var boolConversion = &bindConversion{
+	Decl: "BOOLEAN",
+	In:   "CASE %[1]s WHEN 1 THEN TRUE WHEN 0 THEN FALSE END",
+	Out:  "CASE WHEN %[1]s THEN 1 WHEN NOT %[1]s THEN 0 END",
+}
+
+// buildConvertedCall arma un bloque anónimo que convierte los binds indicados en conversions
+// alrededor de la llamada (por ejemplo BOOLEAN <-> 1/0). En funciones la posición 0 es el retorno.
+// Se usan binds con nombre (:p1, :p2, ...) porque el orden en el texto deja de coincidir con args.
+// argNames (opcional, alineado con args) genera la llamada en notación con nombre (ARG => valor).
+func buildConvertedCall(objectName string, isFunction bool, args []interface{}, conversions map[int]*bindConversion, argNames []string) (string, []interface{}) {
+	decls := []string{}
+	pre := []string{}
+	post := []string{}
+	callArgs := []string{}
+	retTarget := ""
+	named := make([]interface{}, len(args))
+
+	for i, arg := range args {
+		bind := fmt.Sprintf("p%d", i+1)
+		named[i] = sql.Named(bind, arg)
+		expr := ":" + bind
+		if conv := conversions[i]; conv != nil {
+			local := fmt.Sprintf("v%d", i+1)
+			decls = append(decls, fmt.Sprintf("%s %s;", local, conv.Decl))
+			if _, isOut := arg.(sql.Out); isOut {
+				post = append(post, fmt.Sprintf(":%s := %s;", bind, fmt.Sprintf(conv.Out, local)))
+			} else {
+				pre = append(pre, fmt.Sprintf("%s := %s;", local, fmt.Sprintf(conv.In, ":"+bind)))
+			}
+			expr = local
+		}
+		if isFunction && i == 0 {
+			retTarget = expr
+			continue
+		}
+		if argNames != nil && argNames[i] != "" {
+			expr = fmt.Sprintf("%s => %s", argNames[i], expr)
+		}
+		callArgs = append(callArgs, expr)
+	}
+
+	callStmt := fmt.Sprintf("%s(%s);", objectName, strings.Join(callArgs, ", "))
+	if isFunction {
+		callStmt = fmt.Sprintf("%s := %s", retTarget, callStmt)
+	}
+	block := fmt.Sprintf("DECLARE %s BEGIN %s %s %s END;",
+		strings.Join(decls, " "), strings.Join(pre, " "), callStmt, strings.Join(post, " "))
+	return block, named
+}
+
+// boolOutValue convierte el 1/0 leído de un OUT BOOLEAN a true/false/nil
+func boolOutValue(v *sql.NullFloat64) interface{} {
+	if v == nil || !v.Valid {
+		return nil
+	}
+	return v.Float64 != 0
+}
+
 // setupLogFileName genera nombre de archivo de log con estructura: log/{instanceName}/{YYYY-MM-DD}/{instanceName}_{port}_{timestamp}.log
 // Crea las carpetas necesarias automáticamente
 func setupLogFileName(instanceName, port string) string {
@@ -233,7 +431,7 @@ func getDateInputFormats() []string {
 	}
 }
 
-// DeleteJob elimina un job espec├¡fico por ID (memoria y BD)
+// DeleteJob elimina un job específico por ID (memoria y BD)
 func (jm *JobManager) DeleteJob(id string) error {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
@@ -244,6 +442,7 @@ func (jm *JobManager) DeleteJob(id string) error {
 
 	// Eliminar de memoria
 	delete(jm.jobs, id)
+	jm.closeSubscriptions(id)
 
 	// Eliminar de BD
 	if db != nil {
@@ -257,7 +456,7 @@ func (jm *JobManager) DeleteJob(id string) error {
 	return nil
 }
 
-// DeleteJobs elimina m├║ltiples jobs seg├║n criterios
+// DeleteJobs elimina múltiples jobs según criterios
 func (jm *JobManager) DeleteJobs(status []string, olderThanDays int) (int, error) {
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
@@ -291,6 +490,7 @@ func (jm *JobManager) DeleteJobs(status []string, olderThanDays int) (int, error
 	// Eliminar de memoria
 	for _, id := range deleted {
 		delete(jm.jobs, id)
+		jm.closeSubscriptions(id)
 	}
 
 	// Eliminar de BD
@@ -312,7 +512,7 @@ func (jm *JobManager) saveJobToDB(job *AsyncJob) {
 		return
 	}
 
-	// Convertir par├ímetros a JSON
+	// Convertir parámetros a JSON
 	var paramsJSON string
 	if job.Params != nil {
 		if jsonBytes, err := json.Marshal(job.Params); err == nil {
@@ -320,12 +520,26 @@ func (jm *JobManager) saveJobToDB(job *AsyncJob) {
 		}
 	}
 
+	var retryJSON string
+	if job.Retry != nil {
+		if jsonBytes, err := json.Marshal(job.Retry); err == nil {
+			retryJSON = string(jsonBytes)
+		}
+	}
+	var workflowJSON string
+	if job.Workflow != nil {
+		if jsonBytes, err := json.Marshal(job.Workflow); err == nil {
+			workflowJSON = string(jsonBytes)
+		}
+	}
+
 	_, err := db.Exec(`
 		INSERT INTO ASYNC_JOBS (
 			JOB_ID, STATUS, PROCEDURE_NAME, PARAMS, START_TIME, 
-			END_TIME, DURATION, RESULT, ERROR_MSG, PROGRESS
+			END_TIME, DURATION, RESULT, ERROR_MSG, PROGRESS, PRIORITY, CALLBACK_URL, RETRY_POLICY,
+			PARENT_ID, STEP_ID, WORKFLOW, INSTANCE_NAME, HEARTBEAT, ON_INTERRUPT, SCHEMA_NAME, SUBMITTED_BY
 		) VALUES (
-			:1, :2, :3, :4, :5, :6, :7, :8, :9, :10
+			:1, :2, :3, :4, :5, :6, :7, :8, :9, :10, :11, :12, :13, :14, :15, :16, :17, :18, :19, :20, :21
 		)`,
 		job.ID,
 		string(job.Status),
@@ -334,9 +548,20 @@ func (jm *JobManager) saveJobToDB(job *AsyncJob) {
 		job.StartTime,
 		job.EndTime,
 		job.Duration,
-		nil, // RESULT ser├í actualizado despu├®s
+		nil, // RESULT será actualizado después
 		job.Error,
 		job.Progress,
+		job.Priority,
+		job.CallbackURL,
+		retryJSON,
+		job.ParentID,
+		job.Step,
+		workflowJSON,
+		job.Instance,
+		job.Heartbeat,
+		job.OnInterrupt,
+		job.Schema,
+		job.SubmittedBy,
 	)
 
 	if err != nil {
@@ -358,27 +583,176 @@ func (jm *JobManager) updateJobInDB(job *AsyncJob) {
 		}
 	}
 
-	_, err := db.Exec(`
+	var webhookJSON string
+	if job.Webhook != nil {
+		if jsonBytes, err := json.Marshal(job.Webhook); err == nil {
+			webhookJSON = string(jsonBytes)
+		}
+	}
+	var attemptsJSON string
+	if len(job.Attempts) > 0 {
+		if jsonBytes, err := json.Marshal(job.Attempts); err == nil {
+			attemptsJSON = string(jsonBytes)
+		}
+	}
+	var workflowJSON string
+	if job.Workflow != nil {
+		if jsonBytes, err := json.Marshal(job.Workflow); err == nil {
+			workflowJSON = string(jsonBytes)
+		}
+	}
+
+	// Solo se escribe si el job sigue siendo de esta instancia o no tiene dueño: si otra instancia
+	// lo tomó, esta copia está desactualizada y no debe pisar su STATUS/INSTANCE_NAME
+	res, err := db.Exec(`
 		UPDATE ASYNC_JOBS SET
 			STATUS = :1,
-			END_TIME = :2,
-			DURATION = :3,
-			RESULT = :4,
-			ERROR_MSG = :5,
-			PROGRESS = :6
-		WHERE JOB_ID = :7`,
+			START_TIME = :2,
+			END_TIME = :3,
+			DURATION = :4,
+			RESULT = :5,
+			ERROR_MSG = :6,
+			PROGRESS = :7,
+			WEBHOOK = :8,
+			ATTEMPT = :9,
+			ATTEMPTS = :10,
+			NEXT_RETRY = :11,
+			WORKFLOW = :12,
+			INSTANCE_NAME = :13,
+			HEARTBEAT = :14,
+			PROGRESS_MESSAGE = :15
+		WHERE JOB_ID = :16 AND (INSTANCE_NAME = :17 OR INSTANCE_NAME IS NULL)`,
 		string(job.Status),
+		job.StartTime,
 		job.EndTime,
 		job.Duration,
 		resultJSON,
 		job.Error,
 		job.Progress,
+		webhookJSON,
+		job.Attempt,
+		attemptsJSON,
+		job.NextRetry,
+		workflowJSON,
+		job.Instance,
+		job.Heartbeat,
+		job.ProgressMessage,
 		job.ID,
+		jobRecovery.owner,
 	)
 
 	if err != nil {
 		log.Printf("Error actualizando job %s en BD: %v", job.ID, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		log.Printf("[JOBS] Job %s: no se guardó el cambio (%s), el job es de otra instancia o ya no existe", job.ID, job.Status)
+	}
+}
+
+// jobColumns son las columnas de ASYNC_JOBS que lee scanJob
+const jobColumns = `JOB_ID, STATUS, PROCEDURE_NAME, PARAMS, START_TIME,
+		       END_TIME, DURATION, RESULT, ERROR_MSG, PROGRESS, NVL(PRIORITY, 5),
+		       CALLBACK_URL, WEBHOOK, NVL(ATTEMPT, 0), RETRY_POLICY, ATTEMPTS, NEXT_RETRY,
+		       PARENT_ID, STEP_ID, WORKFLOW, INSTANCE_NAME, HEARTBEAT, ON_INTERRUPT, PROGRESS_MESSAGE,
+		       SCHEMA_NAME, SUBMITTED_BY`
+
+// scanJob lee un job de una fila con las columnas de jobColumns
+func scanJob(row interface{ Scan(...interface{}) error }) (*AsyncJob, error) {
+	var job AsyncJob
+	var endTime, nextRetry, heartbeat sql.NullTime
+	var duration, paramsJSON, resultJSON, errorMsg, callbackURL, webhookJSON sql.NullString
+	var retryJSON, attemptsJSON, parentID, stepID, workflowJSON, instance, onInterrupt sql.NullString
+	var progressMessage, schema, submittedBy sql.NullString
+
+	err := row.Scan(&job.ID, &job.Status, &job.ProcName, &paramsJSON, &job.StartTime,
+		&endTime, &duration, &resultJSON, &errorMsg, &job.Progress, &job.Priority,
+		&callbackURL, &webhookJSON, &job.Attempt, &retryJSON, &attemptsJSON, &nextRetry,
+		&parentID, &stepID, &workflowJSON, &instance, &heartbeat, &onInterrupt, &progressMessage,
+		&schema, &submittedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if endTime.Valid {
+		job.EndTime = &endTime.Time
+	}
+	if duration.Valid {
+		job.Duration = duration.String
+	}
+	if paramsJSON.Valid && paramsJSON.String != "" {
+		if err := json.Unmarshal([]byte(paramsJSON.String), &job.Params); err != nil {
+			log.Printf("Error deserializando parámetros del job %s: %v", job.ID, err)
+		}
+	}
+	if resultJSON.Valid && resultJSON.String != "" {
+		if err := json.Unmarshal([]byte(resultJSON.String), &job.Result); err != nil {
+			log.Printf("Error deserializando resultado del job %s: %v", job.ID, err)
+		}
+	}
+	if errorMsg.Valid {
+		job.Error = errorMsg.String
+	}
+	job.CallbackURL = callbackURL.String
+	if webhookJSON.Valid && webhookJSON.String != "" {
+		if err := json.Unmarshal([]byte(webhookJSON.String), &job.Webhook); err != nil {
+			log.Printf("Error deserializando webhook del job %s: %v", job.ID, err)
+		}
+	}
+	if retryJSON.Valid && retryJSON.String != "" {
+		if err := json.Unmarshal([]byte(retryJSON.String), &job.Retry); err != nil {
+			log.Printf("Error deserializando política de reintentos del job %s: %v", job.ID, err)
+		}
+	}
+	if attemptsJSON.Valid && attemptsJSON.String != "" {
+		if err := json.Unmarshal([]byte(attemptsJSON.String), &job.Attempts); err != nil {
+			log.Printf("Error deserializando intentos del job %s: %v", job.ID, err)
+		}
+	}
+	if nextRetry.Valid {
+		job.NextRetry = &nextRetry.Time
+	}
+	job.ParentID = parentID.String
+	job.Step = stepID.String
+	if workflowJSON.Valid && workflowJSON.String != "" {
+		if err := json.Unmarshal([]byte(workflowJSON.String), &job.Workflow); err != nil {
+			log.Printf("Error deserializando workflow del job %s: %v", job.ID, err)
+		}
+	}
+	job.Instance = instance.String
+	if heartbeat.Valid {
+		job.Heartbeat = &heartbeat.Time
+	}
+	job.OnInterrupt = onInterrupt.String
+	job.ProgressMessage = progressMessage.String
+	job.Schema = schema.String
+	job.SubmittedBy = submittedBy.String
+	return &job, nil
+}
+
+// recentJobsFilter selecciona los jobs que se mantienen en memoria: los del último día,
+// los que no terminaron y los que tienen un webhook pendiente
+const recentJobsFilter = `START_TIME >= SYSDATE - 1 OR STATUS IN ('pending', 'running')
+		   OR WEBHOOK LIKE '{"status":"pending"%'`
+
+// queryJobs lee de ASYNC_JOBS los jobs que cumplen la condición indicada
+func queryJobs(where string, args ...interface{}) ([]*AsyncJob, error) {
+	rows, err := db.Query("SELECT "+jobColumns+" FROM ASYNC_JOBS WHERE "+where+" ORDER BY START_TIME DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	jobs := []*AsyncJob{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			log.Printf("Error leyendo job de BD: %v", err)
+			continue
+		}
+		jobs = append(jobs, job)
 	}
+	return jobs, rows.Err()
 }
 
 // LoadJobsFromDB carga jobs desde la base de datos al iniciar
@@ -387,61 +761,22 @@ func (jm *JobManager) LoadJobsFromDB() {
 		return
 	}
 
-	rows, err := db.Query(`
-		SELECT JOB_ID, STATUS, PROCEDURE_NAME, PARAMS, START_TIME,
-		       END_TIME, DURATION, RESULT, ERROR_MSG, PROGRESS
-		FROM ASYNC_JOBS
-		WHERE START_TIME >= SYSDATE - 1
-		ORDER BY START_TIME DESC
-	`)
-
+	jobs, err := queryJobs(recentJobsFilter)
 	if err != nil {
 		if strings.Contains(err.Error(), "ORA-00942") {
-			log.Println("ÔÜá´©Å  Tabla ASYNC_JOBS no existe. Ejecuta: sqlplus @sql/create_async_jobs_table.sql")
+			log.Println("⚠️  Tabla ASYNC_JOBS no existe. Ejecuta: sqlplus @sql/create_async_jobs_table.sql")
 		}
 		return
 	}
-	defer rows.Close()
 
 	jm.mu.Lock()
 	defer jm.mu.Unlock()
-
-	count := 0
-	for rows.Next() {
-		var job AsyncJob
-		var endTime sql.NullTime
-		var duration, paramsJSON, resultJSON, errorMsg sql.NullString
-
-		err := rows.Scan(&job.ID, &job.Status, &job.ProcName, &paramsJSON, &job.StartTime,
-			&endTime, &duration, &resultJSON, &errorMsg, &job.Progress)
-
-		if err == nil {
-			if endTime.Valid {
-				job.EndTime = &endTime.Time
-			}
-			if duration.Valid {
-				job.Duration = duration.String
-			}
-			if paramsJSON.Valid && paramsJSON.String != "" {
-				if err := json.Unmarshal([]byte(paramsJSON.String), &job.Params); err != nil {
-					log.Printf("Error deserializando par├ímetros del job %s: %v", job.ID, err)
-				}
-			}
-			if resultJSON.Valid && resultJSON.String != "" {
-				if err := json.Unmarshal([]byte(resultJSON.String), &job.Result); err != nil {
-					log.Printf("Error deserializando resultado del job %s: %v", job.ID, err)
-				}
-			}
-			if errorMsg.Valid {
-				job.Error = errorMsg.String
-			}
-			jm.jobs[job.ID] = &job
-			count++
-		}
+	for _, job := range jobs {
+		jm.jobs[job.ID] = job
 	}
 
-	if count > 0 {
-		log.Printf("Ô£à Cargados %d jobs desde Oracle", count)
+	if len(jobs) > 0 {
+		log.Printf("✅ Cargados %d jobs desde Oracle", len(jobs))
 	}
 }
 
@@ -459,12 +794,63 @@ func createTableIfNotExists() error {
 	}
 
 	if count > 0 {
-		log.Println("Ô£à Tabla ASYNC_JOBS ya existe")
-		return nil
+		log.Println("✅ Tabla ASYNC_JOBS ya existe")
+		if err := ensureColumn("ASYNC_JOBS", "PRIORITY", "NUMBER(2) DEFAULT 5 NOT NULL"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "CALLBACK_URL", "VARCHAR2(2000)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "WEBHOOK", "CLOB"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "ATTEMPT", "NUMBER(3) DEFAULT 0"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "RETRY_POLICY", "VARCHAR2(1000)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "ATTEMPTS", "CLOB"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "NEXT_RETRY", "TIMESTAMP"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "PARENT_ID", "VARCHAR2(32)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "STEP_ID", "VARCHAR2(100)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "WORKFLOW", "CLOB"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "INSTANCE_NAME", "VARCHAR2(100)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "HEARTBEAT", "TIMESTAMP"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "ON_INTERRUPT", "VARCHAR2(10)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "CANCEL_REQUESTED", "TIMESTAMP"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "PROGRESS_MESSAGE", "VARCHAR2(4000)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "SCHEMA_NAME", "VARCHAR2(128)"); err != nil {
+			return err
+		}
+		if err := ensureColumn("ASYNC_JOBS", "SUBMITTED_BY", "VARCHAR2(200)"); err != nil {
+			return err
+		}
+		return migrateJobStatusCheck()
 	}
 
 	// Crear la tabla
-	log.Println("­ƒôØ Creando tabla ASYNC_JOBS...")
+	log.Println("📝 Creando tabla ASYNC_JOBS...")
 
 	createTableSQL := `
 		CREATE TABLE ASYNC_JOBS (
@@ -478,7 +864,25 @@ func createTableIfNotExists() error {
 			RESULT CLOB,
 			ERROR_MSG CLOB,
 			PROGRESS NUMBER DEFAULT 0,
-			CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+			PRIORITY NUMBER(2) DEFAULT 5 NOT NULL,
+			CALLBACK_URL VARCHAR2(2000),
+			WEBHOOK CLOB,
+			ATTEMPT NUMBER(3) DEFAULT 0,
+			RETRY_POLICY VARCHAR2(1000),
+			ATTEMPTS CLOB,
+			NEXT_RETRY TIMESTAMP,
+			PARENT_ID VARCHAR2(32),
+			STEP_ID VARCHAR2(100),
+			WORKFLOW CLOB,
+			INSTANCE_NAME VARCHAR2(100),
+			HEARTBEAT TIMESTAMP,
+			ON_INTERRUPT VARCHAR2(10),
+			CANCEL_REQUESTED TIMESTAMP,
+			PROGRESS_MESSAGE VARCHAR2(4000),
+			SCHEMA_NAME VARCHAR2(128),
+			SUBMITTED_BY VARCHAR2(200),
+			CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			CONSTRAINT CHK_ASYNC_JOBS_STATUS CHECK (` + jobStatusCheck() + `)
 		)`
 
 	_, err = db.Exec(createTableSQL)
@@ -486,18 +890,59 @@ func createTableIfNotExists() error {
 		return fmt.Errorf("error creando tabla: %v", err)
 	}
 
-	// Crear ├¡ndices
+	// Crear índices
 	if _, err := db.Exec("CREATE INDEX IDX_ASYNC_JOBS_STATUS ON ASYNC_JOBS(STATUS)"); err != nil {
-		log.Printf("ÔÜá´©Å  Error creando ├¡ndice IDX_ASYNC_JOBS_STATUS: %v", err)
+		log.Printf("⚠️  Error creando índice IDX_ASYNC_JOBS_STATUS: %v", err)
 	}
 	if _, err := db.Exec("CREATE INDEX IDX_ASYNC_JOBS_START_TIME ON ASYNC_JOBS(START_TIME)"); err != nil {
-		log.Printf("ÔÜá´©Å  Error creando ├¡ndice IDX_ASYNC_JOBS_START_TIME: %v", err)
+		log.Printf("⚠️  Error creando índice IDX_ASYNC_JOBS_START_TIME: %v", err)
 	}
 	if _, err := db.Exec("CREATE INDEX IDX_ASYNC_JOBS_CREATED_AT ON ASYNC_JOBS(CREATED_AT)"); err != nil {
-		log.Printf("ÔÜá´©Å  Error creando ├¡ndice IDX_ASYNC_JOBS_CREATED_AT: %v", err)
+		log.Printf("⚠️  Error creando índice IDX_ASYNC_JOBS_CREATED_AT: %v", err)
+	}
+	if _, err := db.Exec("CREATE INDEX IDX_ASYNC_JOBS_INSTANCE ON ASYNC_JOBS(INSTANCE_NAME, STATUS)"); err != nil {
+		log.Printf("⚠️  Error creando índice IDX_ASYNC_JOBS_INSTANCE: %v", err)
+	}
+
+	log.Println("✅ Tabla ASYNC_JOBS creada exitosamente")
+	return nil
+}
+
+// ensureColumn agrega una columna a una tabla creada con una versión anterior si todavía no existe
+func ensureColumn(table, column, definition string) error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM USER_TAB_COLUMNS WHERE TABLE_NAME = :1 AND COLUMN_NAME = :2",
+		table, column).Scan(&count)
+	if err != nil || count > 0 {
+		return err
 	}
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("error agregando columna %s.%s: %v", table, column, err)
+	}
+	log.Printf("✅ Columna %s.%s agregada", table, column)
+	return nil
+}
 
-	log.Println("Ô£à Tabla ASYNC_JOBS creada exitosamente")
+// migrateJobStatusCheck actualiza el CHECK de ASYNC_JOBS.STATUS en tablas creadas con una
+// versión anterior (sin alguno de los estados actuales, p.ej. 'cancelled')
+func migrateJobStatusCheck() error {
+	missing := []string{}
+	for _, st := range jobStatuses {
+		missing = append(missing, fmt.Sprintf("INSTR(c.search_condition, '''%s''') = 0", st))
+	}
+	block := fmt.Sprintf(`
+		BEGIN
+			FOR c IN (SELECT constraint_name, search_condition FROM user_constraints
+			          WHERE table_name = 'ASYNC_JOBS' AND constraint_type = 'C') LOOP
+				IF INSTR(c.search_condition, '''pending''') > 0 AND (%s) THEN
+					EXECUTE IMMEDIATE 'ALTER TABLE ASYNC_JOBS DROP CONSTRAINT ' || c.constraint_name;
+					EXECUTE IMMEDIATE 'ALTER TABLE ASYNC_JOBS ADD CONSTRAINT CHK_ASYNC_JOBS_STATUS CHECK (%s)';
+				END IF;
+			END LOOP;
+		END;`, strings.Join(missing, " OR "), strings.ReplaceAll(jobStatusCheck(), "'", "''"))
+	if _, err := db.Exec(block); err != nil {
+		return fmt.Errorf("error actualizando CHECK de ASYNC_JOBS.STATUS: %v", err)
+	}
 	return nil
 }
 
@@ -507,11 +952,11 @@ func createQueryLogTable() error {
 	var tableName string
 	err := db.QueryRow("SELECT table_name FROM user_tables WHERE table_name = 'QUERY_LOG'").Scan(&tableName)
 	if err == nil {
-		log.Println("Ô£à Tabla QUERY_LOG ya existe")
+		log.Println("✅ Tabla QUERY_LOG ya existe")
 		return nil
 	}
 
-	log.Println("­ƒôØ Creando tabla QUERY_LOG...")
+	log.Println("📝 Creando tabla QUERY_LOG...")
 
 	createTableSQL := `
 		CREATE TABLE QUERY_LOG (
@@ -533,21 +978,21 @@ func createQueryLogTable() error {
 		return fmt.Errorf("error creando tabla QUERY_LOG: %v", err)
 	}
 
-	// Crear ├¡ndices
+	// Crear índices
 	if _, err := db.Exec("CREATE INDEX IDX_QUERY_LOG_TYPE ON QUERY_LOG(QUERY_TYPE)"); err != nil {
-		log.Printf("ÔÜá´©Å  Error creando ├¡ndice IDX_QUERY_LOG_TYPE: %v", err)
+		log.Printf("⚠️  Error creando índice IDX_QUERY_LOG_TYPE: %v", err)
 	}
 	if _, err := db.Exec("CREATE INDEX IDX_QUERY_LOG_TIME ON QUERY_LOG(EXECUTION_TIME)"); err != nil {
-		log.Printf("ÔÜá´©Å  Error creando ├¡ndice IDX_QUERY_LOG_TIME: %v", err)
+		log.Printf("⚠️  Error creando índice IDX_QUERY_LOG_TIME: %v", err)
 	}
 	if _, err := db.Exec("CREATE INDEX IDX_QUERY_LOG_SUCCESS ON QUERY_LOG(SUCCESS)"); err != nil {
-		log.Printf("ÔÜá´©Å  Error creando ├¡ndice IDX_QUERY_LOG_SUCCESS: %v", err)
+		log.Printf("⚠️  Error creando índice IDX_QUERY_LOG_SUCCESS: %v", err)
 	}
 	if _, err := db.Exec("CREATE INDEX IDX_QUERY_LOG_CREATED ON QUERY_LOG(CREATED_AT)"); err != nil {
-		log.Printf("ÔÜá´©Å  Error creando ├¡ndice IDX_QUERY_LOG_CREATED: %v", err)
+		log.Printf("⚠️  Error creando índice IDX_QUERY_LOG_CREATED: %v", err)
 	}
 
-	log.Println("Ô£à Tabla QUERY_LOG creada exitosamente")
+	log.Println("✅ Tabla QUERY_LOG creada exitosamente")
 	return nil
 }
 
@@ -588,12 +1033,12 @@ func saveQueryLog(qlog *QueryLog) {
 	} else {
 		elapsed := time.Since(startTime)
 		if elapsed > 100*time.Millisecond {
-			log.Printf("ÔÜá´©Å  saveQueryLog tard├│ %v para log %s", elapsed, qlog.ID)
+			log.Printf("⚠️  saveQueryLog tardó %v para log %s", elapsed, qlog.ID)
 		}
 	}
 }
 
-// generateID genera un ID ├║nico para los logs
+// generateID genera un ID único para los logs
 func generateID() string {
 	b := make([]byte, 16)
 	if _, err := rand.Read(b); err != nil {
@@ -604,7 +1049,7 @@ func generateID() string {
 	return fmt.Sprintf("%x", b)
 }
 
-// setWindowTitle cambia el t├¡tulo de la ventana seg├║n la plataforma
+// setWindowTitle cambia el título de la ventana según la plataforma
 func setWindowTitle(title string) {
 	switch runtime.GOOS {
 	case "windows":
@@ -617,12 +1062,12 @@ func setWindowTitle(title string) {
 		// macOS: escape sequence para Terminal.app
 		fmt.Printf("\033]0;%s\007", title)
 	default:
-		// Otras plataformas: no hacer nada o usar escape sequence gen├®rico
+		// Otras plataformas: no hacer nada o usar escape sequence genérico
 		fmt.Printf("\033]0;%s\007", title)
 	}
 }
 
-// Configuraci├│n de la aplicaci├│n
+// Configuración de la aplicación
 type AppConfig struct {
 	OracleUser     string
 	OraclePassword string
@@ -632,7 +1077,7 @@ type AppConfig struct {
 	ListenPort     string
 }
 
-// Carga la configuraci├│n desde variables de entorno y argumentos, valida obligatorias
+// Carga la configuración desde variables de entorno y argumentos, valida obligatorias
 func loadConfig() AppConfig {
 	envFile := ".env"
 	if len(os.Args) > 1 && os.Args[1] != "" {
@@ -671,6 +1116,15 @@ func loadConfig() AppConfig {
 		os.Exit(2)
 	}
 
+	rules, err := parseStatusRules(os.Getenv("API_STATUS_RULES"))
+	if err != nil {
+		msg := "API_STATUS_RULES: " + err.Error()
+		fmt.Fprintln(os.Stderr, msg)
+		_ = os.WriteFile("log/last_error.txt", []byte(msg+"\n"), 0644)
+		os.Exit(2)
+	}
+	statusRules = rules
+
 	listenPort := os.Getenv("PORT")
 	if listenPort == "" {
 		if len(os.Args) > 2 && os.Args[2] != "" {
@@ -690,7 +1144,7 @@ func loadConfig() AppConfig {
 	}
 }
 
-// Abre la conexi├│n a Oracle y la retorna
+// Abre la conexión a Oracle y la retorna
 func openOracleConnection(cfg AppConfig) (*sql.DB, error) {
 	dsn := fmt.Sprintf("oracle://%s:%s@%s:%s/%s", cfg.OracleUser, cfg.OraclePassword, cfg.OracleHost, cfg.OraclePort, cfg.OracleService)
 	database, err := sql.Open("oracle", dsn)
@@ -699,12 +1153,12 @@ func openOracleConnection(cfg AppConfig) (*sql.DB, error) {
 	}
 
 	// Configurar pool de conexiones para procedimientos largos
-	database.SetMaxOpenConns(25)                  // M├íximo de conexiones abiertas
+	database.SetMaxOpenConns(25)                  // Máximo de conexiones abiertas
 	database.SetMaxIdleConns(5)                   // Conexiones idle
-	database.SetConnMaxLifetime(0)                // Sin l├¡mite de tiempo de vida (0 = infinito)
-	database.SetConnMaxIdleTime(10 * time.Minute) // Cerrar idle despu├®s de 10 min
+	database.SetConnMaxLifetime(0)                // Sin límite de tiempo de vida (0 = infinito)
+	database.SetConnMaxIdleTime(10 * time.Minute) // Cerrar idle después de 10 min
 
-	// Verificar que la conexi├│n sea v├ílida con timeout
+	// Verificar que la conexión sea válida con timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -725,7 +1179,7 @@ func main() {
 		arg := strings.ToLower(os.Args[1])
 		if arg == "-h" || arg == "--help" || arg == "help" {
 			fmt.Print(`
-Go Oracle API - Opciones de ejecuci├│n
+Go Oracle API - Opciones de ejecución
 
 USO:
   go run main.go [archivo_env] [puerto] [nombre_instancia]
@@ -733,11 +1187,11 @@ USO:
 
 Argumentos opcionales:
   archivo_env       Archivo de variables de entorno (por defecto .env)
-  puerto            Puerto donde escuchar├í la API (por defecto 8080)
+  puerto            Puerto donde escuchará la API (por defecto 8080)
   nombre_instancia  Nombre para identificar esta instancia (por defecto auto)
 
-Tambi├®n puedes usar variables de entorno:
-  ENV_FILE          Archivo de configuraci├│n
+También puedes usar variables de entorno:
+  ENV_FILE          Archivo de configuración
   PORT              Puerto de escucha
   INSTANCE_NAME     Nombre de la instancia
 
@@ -748,7 +1202,7 @@ Ejemplos:
   set INSTANCE_NAME=Desarrollo
   go run main.go .env3 8083
 
-Para m├ís informaci├│n consulta:
+Para más información consulta:
   - README.md
   - docs/CONFIGURACION_ENV.md
   - Endpoint /docs
@@ -794,10 +1248,18 @@ Para m├ís informaci├│n consulta:
 	http.HandleFunc("/download", logRequest(authMiddleware(downloadHandler)))
 	http.HandleFunc("/ping", logRequest(authMiddleware(pingHandler)))
 	http.HandleFunc("/query", logRequest(authMiddleware(queryHandler)))
+	http.HandleFunc("/query/async", logRequest(authMiddleware(asyncQueryHandler)))
 	http.HandleFunc("/exec", logRequest(authMiddleware(execHandler)))
 	http.HandleFunc("/procedure", logRequest(authMiddleware(procedureHandler)))
 	http.HandleFunc("/procedure/async", logRequest(authMiddleware(asyncProcedureHandler)))
-	http.HandleFunc("/jobs/", logRequest(authMiddleware(jobsHandler))) // /jobs/{id} y /jobs
+	http.HandleFunc("/table-function", logRequest(authMiddleware(tableFunctionHandler)))
+	http.HandleFunc("/procedures", logRequest(authMiddleware(proceduresHandler)))  // catálogo
+	http.HandleFunc("/procedures/", logRequest(authMiddleware(proceduresHandler))) // /procedures/{nombre}
+	http.HandleFunc("/api/", logRequest(authMiddleware(restAPIHandler)))           // /api/{paquete}/{procedimiento} y /api/openapi.json
+	http.HandleFunc("/jobs/", logRequest(authMiddleware(jobsHandler)))             // /jobs/{id} y /jobs
+	http.HandleFunc("/workflows", logRequest(authMiddleware(workflowsHandler)))    // DAG de procedimientos
+	http.HandleFunc("/schedules", logRequest(authMiddleware(schedulesHandler)))    // programaciones cron
+	http.HandleFunc("/schedules/", logRequest(authMiddleware(schedulesHandler)))   // /schedules/{id}[/pause|/resume]
 
 	// ===============================
 	// 4. Conexión a Oracle
@@ -809,14 +1271,14 @@ Para m├ís informaci├│n consulta:
 
 	db, err = openOracleConnection(cfg)
 	if err != nil {
-		errorMsg := fmt.Sprintf("\nÔØî ERROR FATAL: No se pudo establecer conexi├│n con la base de datos\n\n%v\n\nVerifica:\n1. Que Oracle est├® ejecut├índose\n2. Los datos de conexi├│n en el archivo .env\n3. La conectividad de red al servidor\n4. El firewall y puertos abiertos\n\n", err)
+		errorMsg := fmt.Sprintf("\n❌ ERROR FATAL: No se pudo establecer conexión con la base de datos\n\n%v\n\nVerifica:\n1. Que Oracle esté ejecutándose\n2. Los datos de conexión en el archivo .env\n3. La conectividad de red al servidor\n4. El firewall y puertos abiertos\n\n", err)
 		fmt.Fprint(os.Stderr, errorMsg)
 		_ = os.WriteFile("log/last_error.txt", []byte(errorMsg), 0644)
 		log.Fatal(errorMsg)
 	}
 	defer db.Close()
 
-	fmt.Println("Ô£à Conexi├│n a Oracle establecida correctamente")
+	fmt.Println("✅ Conexión a Oracle establecida correctamente")
 	fmt.Println()
 
 	port := cfg.ListenPort
@@ -825,18 +1287,71 @@ Para m├ís informaci├│n consulta:
 	service := cfg.OracleService
 
 	// ===============================
-	// 5. Inicializar tabla y cargar jobs as├¡ncronos
+	// 5. Inicializar tabla y cargar jobs asíncronos
 	// ===============================
-	if err := createTableIfNotExists(); err != nil {
-		log.Printf("ÔÜá´©Å  No se pudo crear/verificar tabla ASYNC_JOBS: %v", err)
+	jobsTableErr := createTableIfNotExists()
+	if jobsTableErr != nil {
+		log.Printf("⚠️  No se pudo crear/verificar tabla ASYNC_JOBS: %v", jobsTableErr)
 	}
 	if err := createQueryLogTable(); err != nil {
-		log.Printf("ÔÜá´©Å  No se pudo crear/verificar tabla QUERY_LOG: %v", err)
+		log.Printf("⚠️  No se pudo crear/verificar tabla QUERY_LOG: %v", err)
+	}
+	if err := createSchedulesTable(); err != nil {
+		log.Printf("⚠️  No se pudo crear/verificar tabla ASYNC_SCHEDULES: %v", err)
+	}
+	if jobsTableErr == nil {
+		if err := createQueryResultsTable(); err != nil {
+			log.Printf("⚠️  No se pudo crear/verificar tabla ASYNC_QUERY_RESULTS: %v", err)
+		}
+	}
+	if err := createJobProgressObjects(); err != nil {
+		log.Printf("⚠️  No se pudo crear/verificar ASYNC_JOB_PROGRESS y API_JOB_PROGRESS: %v", err)
 	}
 	jobManager.LoadJobsFromDB()
 
 	// ===============================
-	// 7. Detecci├│n de IPs locales
+	// 6. Pool de workers de jobs asíncronos
+	// ===============================
+	jobQueue, err = newJobQueueFromEnv()
+	if err != nil {
+		msg := "Configuración de jobs asíncronos inválida: " + err.Error()
+		fmt.Fprintln(os.Stderr, msg)
+		_ = os.WriteFile("log/last_error.txt", []byte(msg+"\n"), 0644)
+		os.Exit(2)
+	}
+	webhooks, err = newWebhookSenderFromEnv()
+	if err != nil {
+		msg := "Configuración de webhooks inválida: " + err.Error()
+		fmt.Fprintln(os.Stderr, msg)
+		_ = os.WriteFile("log/last_error.txt", []byte(msg+"\n"), 0644)
+		os.Exit(2)
+	}
+	jobRecovery, err = newJobRecoveryFromEnv(jobOwnerName(instanceName, cfg.ListenPort))
+	if err != nil {
+		msg := "Configuración de recuperación de jobs inválida: " + err.Error()
+		fmt.Fprintln(os.Stderr, msg)
+		_ = os.WriteFile("log/last_error.txt", []byte(msg+"\n"), 0644)
+		os.Exit(2)
+	}
+	jobCluster, err = newJobClusterFromEnv(jobsTableErr == nil)
+	if err != nil {
+		msg := "Configuración de la cola compartida inválida: " + err.Error()
+		fmt.Fprintln(os.Stderr, msg)
+		_ = os.WriteFile("log/last_error.txt", []byte(msg+"\n"), 0644)
+		os.Exit(2)
+	}
+	jobQueue.Start()
+	jobRecovery.RecoverOrphanedJobs(true)
+	jobRecovery.Start()
+	requeuePendingJobs()
+	resumeWorkflows()
+	resumeWebhookDeliveries()
+	jobCluster.Start()
+	scheduleManager.Load()
+	scheduleManager.Start()
+
+	// ===============================
+	// 7. Detección de IPs locales
 	// ===============================
 	ips := []string{"0.0.0.0"}
 	if addrs, err := net.InterfaceAddrs(); err == nil {
@@ -857,10 +1372,10 @@ Para m├ís informaci├│n consulta:
 	fmt.Println("==============================")
 	if instanceName != "auto" {
 		fmt.Printf("INSTANCIA: %s\n", instanceName)
-		// Cambiar t├¡tulo de la ventana seg├║n la plataforma
+		// Cambiar título de la ventana según la plataforma
 		setWindowTitle(fmt.Sprintf("Go Oracle API - %s (Puerto %s)", instanceName, port))
 	} else {
-		// T├¡tulo por defecto
+		// Título por defecto
 		setWindowTitle(fmt.Sprintf("Go Oracle API - Puerto %s", port))
 	}
 	fmt.Println("API escuchando en el puerto:", port)
@@ -877,16 +1392,22 @@ Para m├ís informaci├│n consulta:
 	log.Println("- Endpoint de query: /query")
 	log.Println("- Endpoint de exec: /exec")
 	log.Println("- Endpoint de procedure: /procedure")
+	log.Println("- Endpoint de funciones de tabla: /table-function")
+	log.Println("- Endpoint de catálogo: /procedures")
+	log.Println("- Endpoints REST de paquetes: /api/{paquete}/{procedimiento}")
+	log.Println("- Endpoint de jobs asíncronos: /procedure/async, /query/async, /jobs")
+	log.Printf("- Cola de jobs: %v", jobQueue.Stats())
+	log.Println("- Endpoint de programaciones: /schedules")
 	log.Println("- Endpoint de upload: /upload")
 	log.Println("- Endpoint de download: /download")
 	log.Printf("- Conectado a Oracle: usuario=%s host=%s puerto=%s servicio=%s", user, host, port, service)
-	// Estado de conexi├│n a Oracle
+	// Estado de conexión a Oracle
 	if err := db.Ping(); err == nil {
-		log.Printf("- Conexi├│n a Oracle: OK")
+		log.Printf("- Conexión a Oracle: OK")
 	} else {
-		log.Printf("- Conexi├│n a Oracle: ERROR: %v", err)
+		log.Printf("- Conexión a Oracle: ERROR: %v", err)
 		// Registrar error de ping en archivo especial
-		_ = os.WriteFile("log/last_error.txt", []byte(fmt.Sprintf("Error de conexi├│n a Oracle (ping): %v\n", err)), 0644)
+		_ = os.WriteFile("log/last_error.txt", []byte(fmt.Sprintf("Error de conexión a Oracle (ping): %v\n", err)), 0644)
 	}
 	log.Println("==============================")
 	fmt.Println("Endpoints disponibles:")
@@ -898,21 +1419,33 @@ Para m├ís informaci├│n consulta:
 	fmt.Println("  /query     - Ejecuta una consulta SQL (GET)")
 	fmt.Println("  /procedure - Ejecuta un procedimiento almacenado (POST)")
 	fmt.Println("  /procedure/async - Ejecuta un procedimiento en segundo plano (POST)")
-	fmt.Println("  /jobs                - Lista todos los jobs as├¡ncronos (GET)")
+	fmt.Println("  /query/async     - Ejecuta un SELECT en segundo plano y guarda sus filas (POST)")
+	fmt.Println("  /table-function      - Ejecuta una función de tabla (pipelined) con paginación (POST)")
+	fmt.Println("  /procedures          - Lista procedimientos y funciones: ?schema=&package=&q= (GET)")
+	fmt.Println("  /procedures/{nombre} - Firma de un programa: ESQUEMA.PAQUETE.NOMBRE (GET)")
+	fmt.Println("  /api/{paquete}/{proc} - Ejecuta un procedimiento de un paquete de API_PACKAGES (POST)")
+	fmt.Println("  /api/openapi.json    - Documento OpenAPI 3 de los paquetes publicados (GET)")
+	fmt.Println("  /jobs                - Lista jobs asíncronos: ?status=&procedure=&from=&to=&sort=&limit=&cursor= (GET)")
 	fmt.Println("  /jobs?status=...     - Elimina jobs por status: completed,failed (DELETE)")
-	fmt.Println("  /jobs?older_than=7   - Elimina jobs m├ís antiguos que N d├¡as (DELETE)")
-	fmt.Println("  /jobs/{id}           - Consulta el estado de un job espec├¡fico (GET)")
-	fmt.Println("  /jobs/{id}           - Elimina un job espec├¡fico (DELETE)")
+	fmt.Println("  /jobs?older_than=7   - Elimina jobs más antiguos que N días (DELETE)")
+	fmt.Println("  /jobs/{id}           - Consulta el estado de un job específico (GET)")
+	fmt.Println("  /jobs/{id}           - Elimina un job específico (DELETE)")
+	fmt.Println("  /jobs/{id}/cancel    - Cancela un job pendiente o en ejecución (POST)")
+	fmt.Println("  /jobs/{id}/rows      - Filas de una consulta de /query/async: ?offset=&limit=&format=json|csv (GET)")
+	fmt.Println("  /schedules           - Lista (GET) o crea (POST) ejecuciones programadas con cron")
+	fmt.Println("  /schedules/{id}      - Consulta con próximas ejecuciones (GET) o elimina (DELETE)")
+	fmt.Println("  /schedules/{id}/pause, /resume - Pausa o reanuda una programación (POST)")
+	fmt.Println("  /schedules/preview?cron=...&timezone=... - Próximas ejecuciones de una expresión (GET)")
 	fmt.Println("  /upload    - Sube un archivo como BLOB (POST)")
 	fmt.Println("  /download  - Descarga un archivo BLOB por ID (GET)")
 	fmt.Println("              Params: id (requerido), table (opcional, default: archivos)")
 	fmt.Println("              Ejemplo: /download?id=123 o /download?id=123&table=documentos")
-	fmt.Println("\nPara detalles de uso y ejemplos, consulta la documentaci├│n en:")
+	fmt.Println("\nPara detalles de uso y ejemplos, consulta la documentación en:")
 	fmt.Println("  - /docs (endpoint)")
 	fmt.Println("  - docs/USO_Y_PRUEBAS.md (archivo)")
 
 	// ===============================
-	// 9. Iniciar limpieza peri├│dica de jobs antiguos
+	// 9. Iniciar limpieza periódica de jobs antiguos
 	// ===============================
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
@@ -930,8 +1463,10 @@ Para m├ís informaci├│n consulta:
 		Addr:    ":" + port,
 		Handler: http.DefaultServeMux,
 	}
+	// Cerrar los streams SSE y long polling abiertos para no demorar el apagado
+	srv.RegisterOnShutdown(jobManager.CloseAllSubscriptions)
 
-	// Canal para se├▒ales del sistema
+	// Canal para señales del sistema
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
 
@@ -943,7 +1478,7 @@ Para m├ís informaci├│n consulta:
 	}()
 
 	<-quit
-	log.Println("\nSe├▒al de apagado recibida, cerrando servidor...")
+	log.Println("\nSeñal de apagado recibida, cerrando servidor...")
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
@@ -952,14 +1487,14 @@ Para m├ís informaci├│n consulta:
 	log.Println("Servidor cerrado correctamente.")
 }
 
-// docsHandler sirve el contenido del README.md como documentaci├│n b├ísica
+// docsHandler sirve el contenido del README.md como documentación básica
 func docsHandler(w http.ResponseWriter, r *http.Request) {
 	enableCORS(&w, r)
 	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
 	data, err := os.ReadFile("README.md")
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("Error leyendo la documentaci├│n: " + err.Error()))
+		w.Write([]byte("Error leyendo la documentación: " + err.Error()))
 		return
 	}
 	w.Write(data)
@@ -983,19 +1518,24 @@ func logsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(data)
 }
 
-// logRequest es un middleware que registra cada petici├│n HTTP entrante
+// clientIP retorna la IP del cliente (sin el puerto)
+func clientIP(r *http.Request) string {
+	ip := r.RemoteAddr
+	if colon := strings.LastIndex(ip, ":"); colon != -1 {
+		ip = ip[:colon]
+	}
+	return ip
+}
+
+// logRequest es un middleware que registra cada petición HTTP entrante
 func logRequest(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ip := r.RemoteAddr
-		if colon := strings.LastIndex(ip, ":"); colon != -1 {
-			ip = ip[:colon]
-		}
-		log.Printf("%s %s desde %s", r.Method, r.URL.Path, ip)
+		log.Printf("%s %s desde %s", r.Method, r.URL.Path, clientIP(r))
 		next(w, r)
 	}
 }
 
-// uploadHandler recibe archivos v├¡a multipart/form-data y los guarda en una tabla BLOB
+// uploadHandler recibe archivos vía multipart/form-data y los guarda en una tabla BLOB
 func uploadHandler(w http.ResponseWriter, r *http.Request) {
 	enableCORS(&w, r)
 	if r.Method == http.MethodOptions {
@@ -1007,7 +1547,7 @@ func uploadHandler(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite POST"})
 		return
 	}
-	err := r.ParseMultipartForm(100 << 20) // 100 MB m├íximo
+	err := r.ParseMultipartForm(100 << 20) // 100 MB máximo
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(map[string]string{"error": "Error al leer el archivo: " + err.Error()})
@@ -1057,13 +1597,13 @@ func downloadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Obtener par├ímetros de la query
+	// Obtener parámetros de la query
 	id := r.URL.Query().Get("id")
 	table := r.URL.Query().Get("table")
 
 	if id == "" {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Falta el par├ímetro 'id'"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "Falta el parámetro 'id'"})
 		return
 	}
 
@@ -1073,11 +1613,11 @@ func downloadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Validar nombre de tabla para prevenir SQL injection
-	// Solo permitir letras, n├║meros y guiones bajos, m├íximo 30 caracteres
+	// Solo permitir letras, números y guiones bajos, máximo 30 caracteres
 	validTableName := regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
 	if !validTableName.MatchString(table) || len(table) > 30 {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Nombre de tabla inv├ílido"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "Nombre de tabla inválido"})
 		return
 	}
 
@@ -1099,7 +1639,7 @@ func downloadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Detectar tipo MIME basado en la extensi├│n del archivo
+	// Detectar tipo MIME basado en la extensión del archivo
 	contentType := "application/octet-stream"
 	if strings.HasSuffix(strings.ToLower(nombre), ".pdf") {
 		contentType = "application/pdf"
@@ -1123,7 +1663,7 @@ func downloadHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write(contenido)
 }
 
-// procedureHandler ejecuta un procedimiento almacenado con par├ímetros IN y OUT
+// procedureHandler ejecuta un procedimiento almacenado con parámetros IN y OUT
 func procedureHandler(w http.ResponseWriter, r *http.Request) {
 	enableCORS(&w, r)
 	w.Header().Set("Content-Type", "application/json")
@@ -1137,20 +1677,10 @@ func procedureHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Name   string `json:"name"`
-		Schema string `json:"schema,omitempty"` // Esquema del procedimiento/funci├│n
-		Params []struct {
-			Name      string      `json:"name"`
-			Value     interface{} `json:"value,omitempty"`
-			Direction string      `json:"direction,omitempty"`
-			Type      string      `json:"type,omitempty"` // "number", "string", "date"
-		} `json:"params"`
-		IsFunction bool `json:"isFunction,omitempty"`
-	}
+	var req ProcedureRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inv├ílido"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
 		return
 	}
 
@@ -1158,312 +1688,18 @@ func procedureHandler(w http.ResponseWriter, r *http.Request) {
 	reqJSON, _ := json.MarshalIndent(req, "", "  ")
 	log.Printf("[PROCEDURE] JSON recibido:\n%s", string(reqJSON))
 
-	if req.Name == "" {
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "Falta el campo 'name'"})
+	if err := prepareProcedureRequest(r.Context(), &req); err != nil {
+		w.WriteHeader(procedureErrorStatus(err))
+		json.NewEncoder(w).Encode(procedureErrorBody(err))
 		return
 	}
 
 	if req.Schema != "" {
-		log.Printf("[PROCEDURE] Ejecutando: %s.%s con %d par├ímetros", req.Schema, req.Name, len(req.Params))
+		log.Printf("[PROCEDURE] Ejecutando: %s.%s con %d parámetros", req.Schema, req.Name, len(req.Params))
 	} else {
-		log.Printf("[PROCEDURE] Ejecutando: %s con %d par├ímetros", req.Name, len(req.Params))
-	}
-
-	placeholders := []string{}
-	args := []interface{}{}
-	outIndexes := make(map[int]string)
-	outBuffers := make(map[int]*string)
-	outNumMap := make(map[int]*sql.NullFloat64)
-	outDateMap := make(map[int]*sql.NullTime)
-
-	// Si es función, el primer OUT es el valor de retorno
-	if req.IsFunction {
-		// Buscar el primer parámetro OUT (valor de retorno)
-		retIndex := -1
-		for i, p := range req.Params {
-			if strings.ToUpper(p.Direction) == "OUT" {
-				retIndex = i
-				break
-			}
-		}
-		if retIndex == -1 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Debe incluir un parámetro OUT para el valor de retorno"})
-			return
-		}
-
-		// PASO 1: Preasignar buffers para OUT parámetros en funciones
-		functionOutBuffers := make([]*string, 0)
-		functionOutNums := make([]*sql.NullFloat64, 0)
-		functionOutDates := make([]*sql.NullTime, 0)
-		functionOutIndex := 0
-
-		// Retorno de función (primer OUT)
-		p := req.Params[retIndex]
-		if strings.ToLower(p.Type) == "date" {
-			outDate := sql.NullTime{}
-			functionOutDates = append(functionOutDates, &outDate)
-			outIndexes[0] = p.Name
-			outDateMap[0] = &outDate
-		} else if strings.ToLower(p.Type) == "number" || strings.Contains(strings.ToLower(p.Name), "resultado") || strings.Contains(strings.ToLower(p.Name), "total") || strings.Contains(strings.ToLower(p.Name), "count") || strings.Contains(strings.ToLower(p.Name), "suma") || strings.Contains(strings.ToLower(p.Name), "num") {
-			outNum := sql.NullFloat64{}
-			functionOutNums = append(functionOutNums, &outNum)
-			outIndexes[0] = p.Name
-			outNumMap[0] = &outNum
-		} else {
-			outStr := strings.Repeat(" ", 4000)
-			functionOutBuffers = append(functionOutBuffers, &outStr)
-			outIndexes[0] = p.Name
-			outBuffers[0] = &outStr
-		}
-		functionOutIndex++
-
-		// Preasignar el resto de OUT parámetros
-		for i, p := range req.Params {
-			if i == retIndex {
-				continue
-			}
-			if strings.ToUpper(p.Direction) == "OUT" {
-				lowerName := strings.ToLower(p.Name)
-				isDate := strings.ToLower(p.Type) == "date"
-				isNumeric := strings.ToLower(p.Type) == "number" ||
-					strings.Contains(lowerName, "resultado") || strings.Contains(lowerName, "result") ||
-					strings.Contains(lowerName, "total") || strings.Contains(lowerName, "count") ||
-					strings.Contains(lowerName, "suma") || strings.Contains(lowerName, "num") ||
-					strings.Contains(lowerName, "int") || strings.Contains(lowerName, "id")
-
-				if isDate {
-					outDate := sql.NullTime{}
-					functionOutDates = append(functionOutDates, &outDate)
-					outIndexes[functionOutIndex] = p.Name
-					outDateMap[functionOutIndex] = &outDate
-				} else if isNumeric {
-					outNum := sql.NullFloat64{}
-					functionOutNums = append(functionOutNums, &outNum)
-					outIndexes[functionOutIndex] = p.Name
-					outNumMap[functionOutIndex] = &outNum
-				} else {
-					outStr := strings.Repeat(" ", 4000)
-					functionOutBuffers = append(functionOutBuffers, &outStr)
-					outIndexes[functionOutIndex] = p.Name
-					outBuffers[functionOutIndex] = &outStr
-				}
-				functionOutIndex++
-			}
-		}
-
-		// PASO 2: Construir placeholders y argumentos para funciones
-		placeholders = append(placeholders, ":1")
-
-		// Retorno de función
-		p = req.Params[retIndex]
-		if strings.ToLower(p.Type) == "date" {
-			args = append(args, sql.Out{Dest: functionOutDates[0], In: false})
-		} else if strings.ToLower(p.Type) == "number" || strings.Contains(strings.ToLower(p.Name), "resultado") || strings.Contains(strings.ToLower(p.Name), "total") || strings.Contains(strings.ToLower(p.Name), "count") || strings.Contains(strings.ToLower(p.Name), "suma") || strings.Contains(strings.ToLower(p.Name), "num") {
-			args = append(args, sql.Out{Dest: functionOutNums[0], In: false})
-		} else {
-			args = append(args, sql.Out{Dest: functionOutBuffers[0], In: false})
-		}
-
-		// Resto de parámetros
-		paramPos := 2
-		functionOutIndex = 1
-		for i, p := range req.Params {
-			if i == retIndex {
-				continue
-			}
-			placeholders = append(placeholders, fmt.Sprintf(":%d", paramPos))
-			if strings.ToUpper(p.Direction) == "OUT" {
-				lowerName := strings.ToLower(p.Name)
-				isDate := strings.ToLower(p.Type) == "date"
-				isNumeric := strings.ToLower(p.Type) == "number" ||
-					strings.Contains(lowerName, "resultado") || strings.Contains(lowerName, "result") ||
-					strings.Contains(lowerName, "total") || strings.Contains(lowerName, "count") ||
-					strings.Contains(lowerName, "suma") || strings.Contains(lowerName, "num") ||
-					strings.Contains(lowerName, "int") || strings.Contains(lowerName, "id")
-
-				if isDate {
-					args = append(args, sql.Out{Dest: functionOutDates[functionOutIndex], In: false})
-				} else if isNumeric {
-					args = append(args, sql.Out{Dest: functionOutNums[functionOutIndex], In: false})
-				} else {
-					args = append(args, sql.Out{Dest: functionOutBuffers[functionOutIndex], In: false})
-				}
-				functionOutIndex++
-			} else {
-				// Parámetro IN: verificar si es fecha
-				pTypeLower := strings.ToLower(p.Type)
-				pNameLower := strings.ToLower(p.Name)
-				isDateType := pTypeLower == "date"
-				isDateName := strings.Contains(pNameLower, "fecha") || strings.Contains(pNameLower, "periodo")
-
-				if isDateType || isDateName {
-					// Intentar parsear como fecha
-					if parsedTime, err := parseDateParam(p.Value); err == nil {
-						args = append(args, parsedTime)
-					} else {
-						log.Printf("[PROCEDURE] Advertencia al parsear fecha en parámetro '%s': %v", p.Name, err)
-						args = append(args, p.Value)
-					}
-				} else {
-					args = append(args, p.Value)
-				}
-			}
-			paramPos++
-		}
-
-		// Formatear el nombre para manejar esquema.funci├│n correctamente
-		functionName := formatObjectName(req.Schema, req.Name)
-
-		call := fmt.Sprintf("BEGIN :1 := %s(%s); END;", functionName, strings.Join(placeholders[1:], ", "))
-		log.Printf("[PROCEDURE] SQL generado para funci├│n: %s", call)
-
-		stmt, err := db.Prepare(call)
-		if err != nil {
-			errorMsg := err.Error()
-
-			// Mejorar el mensaje de error
-			if strings.Contains(errorMsg, "PLS-00201") {
-				errorMsg = fmt.Sprintf("Funci├│n '%s' no encontrada. Verifica que existe en la base de datos.", req.Name)
-			} else if strings.Contains(errorMsg, "PLS-00306") {
-				errorMsg = fmt.Sprintf("Par├ímetros incorrectos para '%s'. Verifica tipos y cantidad de par├ímetros.", req.Name)
-			}
-
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": errorMsg})
-			return
-		}
-		defer stmt.Close()
-
-		if _, err := stmt.Exec(args...); err != nil {
-			errorMsg := err.Error()
-
-			// Mejorar mensajes de error comunes
-			if strings.Contains(errorMsg, "ORA-06502") {
-				errorMsg = "Error de conversi├│n de tipos. Verifica que los tipos de datos sean correctos."
-			} else if strings.Contains(errorMsg, "ORA-01403") {
-				errorMsg = "No se encontraron datos. La funci├│n no retorn├│ resultados."
-			}
-
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": errorMsg})
-			return
-		}
-
-		out := make(map[string]interface{})
-		for i, name := range outIndexes {
-			if datePtr, ok := outDateMap[i]; ok && datePtr != nil {
-				if datePtr.Valid {
-					out[name] = formatDateOutput(datePtr.Time)
-				} else {
-					out[name] = nil
-				}
-				continue
-			}
-			if numPtr, ok := outNumMap[i]; ok && numPtr != nil {
-				if numPtr.Valid {
-					out[name] = numPtr.Float64
-				} else {
-					out[name] = nil
-				}
-				continue
-			}
-			if ptr, ok := outBuffers[i]; ok && ptr != nil {
-				out[name] = *ptr
-			}
-		}
-		json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "out": out})
-		return
-	}
-
-	// Procedimiento normal (no función)
-	// PASO 1: Preasignar buffers para OUT parámetros
-	// Esto evita que las variables locales pierdan scope después del loop
-	outParamIndex := 0
-	outBuffersArray := make([]*string, 0)
-	outNumsArray := make([]*sql.NullFloat64, 0)
-	outDatesArray := make([]*sql.NullTime, 0)
-
-	for _, p := range req.Params {
-		if strings.ToUpper(p.Direction) == "OUT" {
-			lowerName := strings.ToLower(p.Name)
-			isDate := strings.ToLower(p.Type) == "date"
-			isNumeric := strings.ToLower(p.Type) == "number" ||
-				strings.Contains(lowerName, "resultado") || strings.Contains(lowerName, "result") ||
-				strings.Contains(lowerName, "total") || strings.Contains(lowerName, "count") ||
-				strings.Contains(lowerName, "suma") || strings.Contains(lowerName, "num") ||
-				strings.Contains(lowerName, "int") || strings.Contains(lowerName, "id")
-
-			if isDate {
-				outDate := sql.NullTime{}
-				outDatesArray = append(outDatesArray, &outDate)
-				outIndexes[outParamIndex] = p.Name
-				outDateMap[outParamIndex] = &outDate
-			} else if isNumeric {
-				outNum := sql.NullFloat64{}
-				outNumsArray = append(outNumsArray, &outNum)
-				outIndexes[outParamIndex] = p.Name
-				outNumMap[outParamIndex] = &outNum
-			} else {
-				outStr := strings.Repeat(" ", 4000)
-				outBuffersArray = append(outBuffersArray, &outStr)
-				outIndexes[outParamIndex] = p.Name
-				outBuffers[outParamIndex] = &outStr
-			}
-			outParamIndex++
-		}
-	}
-
-	// PASO 2: Procesar parámetros y construir argumentos
-	outParamIndex = 0
-	for i, p := range req.Params {
-		placeholders = append(placeholders, fmt.Sprintf(":%d", i+1))
-		if strings.ToUpper(p.Direction) == "OUT" {
-			lowerName := strings.ToLower(p.Name)
-			isDate := strings.ToLower(p.Type) == "date"
-			isNumeric := strings.ToLower(p.Type) == "number" ||
-				strings.Contains(lowerName, "resultado") || strings.Contains(lowerName, "result") ||
-				strings.Contains(lowerName, "total") || strings.Contains(lowerName, "count") ||
-				strings.Contains(lowerName, "suma") || strings.Contains(lowerName, "num") ||
-				strings.Contains(lowerName, "int") || strings.Contains(lowerName, "id")
-
-			if isDate {
-				args = append(args, sql.Out{Dest: outDatesArray[outParamIndex], In: false})
-			} else if isNumeric {
-				args = append(args, sql.Out{Dest: outNumsArray[outParamIndex], In: false})
-			} else {
-				args = append(args, sql.Out{Dest: outBuffersArray[outParamIndex], In: false})
-			}
-			outParamIndex++
-		} else {
-			// Verificar si es fecha por tipo explícito o por nombre
-			pTypeLower := strings.ToLower(p.Type)
-			pNameLower := strings.ToLower(p.Name)
-			isDateType := pTypeLower == "date"
-			isDateName := strings.Contains(pNameLower, "fecha") || strings.Contains(pNameLower, "periodo")
-
-			if isDateType || isDateName {
-				// Intentar parsear como fecha usando parseDateParam
-				if parsedTime, err := parseDateParam(p.Value); err == nil {
-					args = append(args, parsedTime)
-				} else {
-					// Si falla, loguear y usar valor original
-					log.Printf("[PROCEDURE] Advertencia al parsear fecha en parámetro '%s': %v", p.Name, err)
-					args = append(args, p.Value)
-				}
-			} else {
-				args = append(args, p.Value)
-			}
-		}
+		log.Printf("[PROCEDURE] Ejecutando: %s con %d parámetros", req.Name, len(req.Params))
 	}
 
-	// Formatear el nombre para manejar esquema.procedimiento correctamente
-	procedureName := formatObjectName(req.Schema, req.Name)
-
-	call := fmt.Sprintf("BEGIN %s(%s); END;", procedureName, strings.Join(placeholders, ", "))
-	log.Printf("[PROCEDURE] SQL generado para procedimiento: %s", call)
-
 	// Crear log
 	startExec := time.Now()
 	paramsJSON, _ := json.Marshal(req.Params)
@@ -1476,80 +1712,40 @@ func procedureHandler(w http.ResponseWriter, r *http.Request) {
 		UserIP:        r.RemoteAddr,
 	}
 
-	stmt, err := db.Prepare(call)
+	result, err := executeProcedure(r.Context(), &req, nil)
 	if err != nil {
-		errorMsg := err.Error()
-
-		// Mejorar el mensaje de error
-		if strings.Contains(errorMsg, "PLS-00201") {
-			errorMsg = fmt.Sprintf("Procedimiento '%s' no encontrado. Verifica que existe en la base de datos.", req.Name)
-		} else if strings.Contains(errorMsg, "PLS-00306") {
-			errorMsg = fmt.Sprintf("Par├ímetros incorrectos para '%s'. Verifica tipos y cantidad de par├ímetros.", req.Name)
-		}
-
-		qlog.Success = false
-		qlog.ErrorMsg = errorMsg
-		qlog.Duration = time.Since(startExec).String()
-		go saveQueryLog(qlog)
-
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": errorMsg})
-		return
-	}
-	defer stmt.Close()
-
-	if _, err := stmt.Exec(args...); err != nil {
-		errorMsg := err.Error()
-
-		// Mejorar mensajes de error comunes
-		if strings.Contains(errorMsg, "ORA-06502") {
-			errorMsg = "Error de conversi├│n de tipos. Verifica que los tipos de datos sean correctos."
-		} else if strings.Contains(errorMsg, "ORA-01403") {
-			errorMsg = "No se encontraron datos. El procedimiento no retorn├│ resultados."
-		}
-
 		qlog.Success = false
-		qlog.ErrorMsg = errorMsg
+		qlog.ErrorMsg = err.Error()
 		qlog.Duration = time.Since(startExec).String()
 		go saveQueryLog(qlog)
 
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": errorMsg})
+		w.WriteHeader(procedureErrorStatus(err))
+		json.NewEncoder(w).Encode(procedureErrorBody(err))
 		return
 	}
 
-	out := make(map[string]interface{})
-	for i, name := range outIndexes {
-		if datePtr, ok := outDateMap[i]; ok && datePtr != nil {
-			if datePtr.Valid {
-				out[name] = formatDateOutput(datePtr.Time)
-			} else {
-				out[name] = nil
-			}
-			continue
-		}
-		if numPtr, ok := outNumMap[i]; ok && numPtr != nil {
-			if numPtr.Valid {
-				out[name] = numPtr.Float64
-			} else {
-				out[name] = nil
-			}
-			continue
-		}
-		if ptr, ok := outBuffers[i]; ok && ptr != nil {
-			out[name] = *ptr
-		}
-	}
-
 	qlog.Success = true
-	qlog.RowsAffected = int64(len(out))
+	qlog.RowsAffected = int64(len(result.Out))
 	qlog.Duration = time.Since(startExec).String()
 	go saveQueryLog(qlog)
 
-	json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "out": out})
+	resp := map[string]interface{}{"status": "ok", "out": result.Out}
+	if len(result.ResultSets) > 0 {
+		resp["result_sets"] = result.ResultSets
+		if result.Truncated {
+			resp["result_sets_truncated"] = true
+		}
+	}
+	if len(result.Warnings) > 0 {
+		resp["warnings"] = result.Warnings
+	}
+	if req.CaptureOutput {
+		resp["dbms_output"] = result.Output
+	}
+	json.NewEncoder(w).Encode(resp)
 }
 
-// asyncProcedureHandler ejecuta un procedimiento de forma as├¡ncrona
+// asyncProcedureHandler ejecuta un procedimiento de forma asíncrona
 func asyncProcedureHandler(w http.ResponseWriter, r *http.Request) {
 	enableCORS(&w, r)
 	w.Header().Set("Content-Type", "application/json")
@@ -1563,28 +1759,93 @@ func asyncProcedureHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var req struct {
-		Name       string `json:"name"`
-		Schema     string `json:"schema,omitempty"` // Esquema del procedimiento/funci├│n
-		IsFunction bool   `json:"isFunction"`
-		Params     []struct {
-			Name      string      `json:"name"`
-			Value     interface{} `json:"value,omitempty"`
-			Direction string      `json:"direction"`
-			Type      string      `json:"type,omitempty"`
-		} `json:"params"`
+	var body asyncProcedureRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
+		return
 	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	req := body.ProcedureRequest
+	priority, err := jobPriority(body.Priority)
+	if err == nil && body.CallbackURL != "" {
+		err = webhooks.validateCallbackURL(body.CallbackURL)
+	}
+	if err == nil && body.Retry != nil {
+		err = body.Retry.normalize()
+	}
+	if err == nil {
+		body.OnInterrupt, err = parseOnInterrupt(body.OnInterrupt)
+	}
+	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inv├ílido"})
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if err := prepareProcedureRequest(r.Context(), &req); err != nil {
+		w.WriteHeader(procedureErrorStatus(err))
+		json.NewEncoder(w).Encode(procedureErrorBody(err))
+		return
+	}
+
+	if jobQueue.Full() {
+		writeQueueFull(w)
+		return
+	}
+
+	// Crear el job con los parámetros; queda pending hasta que lo tome un worker
+	job, err := submitProcedureJob(req, procedureRequestToParams(&req), jobOptions{
+		Priority:    priority,
+		CallbackURL: body.CallbackURL,
+		Retry:       body.Retry,
+		OnInterrupt: body.OnInterrupt,
+		SubmittedBy: clientIP(r),
+	})
+	if err != nil {
+		writeQueueFull(w)
 		return
 	}
 
-	// Preparar par├ímetros para guardar en el job
+	// Responder inmediatamente con el ID del job
+	resp := map[string]interface{}{
+		"status":           "accepted",
+		"job_id":           job.ID,
+		"priority":         job.Priority,
+		"message":          "Procedimiento encolado para ejecutarse en segundo plano",
+		"check_status_url": fmt.Sprintf("/jobs/%s", job.ID),
+		"on_interrupt":     job.OnInterrupt,
+	}
+	if job.Retry != nil {
+		resp["retry"] = job.Retry
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// writeQueueFull responde 503 con Retry-After cuando la cola de jobs está llena
+func writeQueueFull(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(asyncRetryAfterSeconds))
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": "La cola de jobs asíncronos está llena, reintenta más tarde",
+		"queue": jobQueue.Stats(),
+	})
+}
+
+// procedureRequestToParams arma el mapa de parámetros que se guarda en el job
+func procedureRequestToParams(req *ProcedureRequest) map[string]interface{} {
 	paramsMap := make(map[string]interface{})
 	paramsMap["name"] = req.Name
+	if req.Schema != "" {
+		paramsMap["schema"] = req.Schema
+	}
 	paramsMap["isFunction"] = req.IsFunction
+	if req.Overload > 0 {
+		paramsMap["overload"] = req.Overload
+	}
+	if req.CaptureOutput {
+		paramsMap["capture_output"] = true
+	}
 	paramsArray := []map[string]interface{}{}
 	for _, p := range req.Params {
 		paramObj := map[string]interface{}{
@@ -1597,222 +1858,139 @@ func asyncProcedureHandler(w http.ResponseWriter, r *http.Request) {
 		if p.Type != "" {
 			paramObj["type"] = p.Type
 		}
+		if p.TypeName != "" {
+			paramObj["type_name"] = p.TypeName
+		}
 		paramsArray = append(paramsArray, paramObj)
 	}
 	paramsMap["params"] = paramsArray
+	return paramsMap
+}
 
-	// Crear el job con los par├ímetros
-	job := jobManager.CreateJob(req.Name, paramsMap)
-
-	// Responder inmediatamente con el ID del job
-	w.WriteHeader(http.StatusAccepted)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"status":           "accepted",
-		"job_id":           job.ID,
-		"message":          "Procedimiento ejecut├índose en segundo plano",
-		"check_status_url": fmt.Sprintf("/jobs/%s", job.ID),
-	})
-
-	// Ejecutar el procedimiento en una goroutine
-	go func() {
-		// Capturar panics para evitar que el job quede colgado
-		defer func() {
-			if r := recover(); r != nil {
-				endTime := time.Now()
-				jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
-					j.Status = JobStatusFailed
-					j.Error = fmt.Sprintf("Panic recuperado: %v", r)
-					j.EndTime = &endTime
-					j.Duration = endTime.Sub(j.StartTime).String()
-					j.Progress = 100
-				})
-				log.Printf("ÔØî Panic en job %s: %v", job.ID, r)
-			}
-		}()
-
-		// Actualizar estado a running
-		jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
-			j.Status = JobStatusRunning
-			j.Progress = 10
-		})
-
-		// Preparar par├ímetros igual que en procedureHandler
-		placeholders := []string{}
-		args := []interface{}{}
-		outIndexes := []string{}
-		outBuffers := make(map[int]*string)
-		outNumMap := make(map[int]*sql.NullFloat64)
-		outDateMap := make(map[int]*sql.NullTime)
-
-		if req.IsFunction {
-			outIdx := 0
-			placeholders = append(placeholders, ":1")
-			ptr := new(string)
-			*ptr = ""
-			outBuffers[outIdx] = ptr
-			args = append(args, sql.Out{Dest: ptr})
-			outIndexes = append(outIndexes, "return_value")
-		}
-
-		for _, p := range req.Params {
-			if p.Direction == "OUT" {
-				outIdx := len(outIndexes)
-				placeholders = append(placeholders, fmt.Sprintf(":%d", len(placeholders)+1))
-
-				// Verificar tipo expl├¡cito o inferir por nombre
-				pType := strings.ToLower(p.Type)
-				pNameLower := strings.ToLower(p.Name)
-				isDate := pType == "date"
-				isNumber := false
-
-				if !isDate {
-					if pType == "number" || pType == "integer" || pType == "float" {
-						isNumber = true
-					} else if pType == "" {
-						numberKeywords := []string{"resultado", "result", "total", "count", "suma", "num", "int", "id"}
-						for _, kw := range numberKeywords {
-							if strings.Contains(pNameLower, kw) {
-								isNumber = true
-								break
-							}
-						}
-					}
-				}
-
-				if isDate {
-					datePtr := new(sql.NullTime)
-					outDateMap[outIdx] = datePtr
-					args = append(args, sql.Out{Dest: datePtr})
-				} else if isNumber {
-					numPtr := new(sql.NullFloat64)
-					outNumMap[outIdx] = numPtr
-					args = append(args, sql.Out{Dest: numPtr})
-				} else {
-					ptr := new(string)
-					*ptr = strings.Repeat(" ", 4000)
-					outBuffers[outIdx] = ptr
-					args = append(args, sql.Out{Dest: ptr})
-				}
-				outIndexes = append(outIndexes, p.Name)
-			} else {
-				placeholders = append(placeholders, fmt.Sprintf(":%d", len(placeholders)+1))
-
-				// Verificar si es fecha por tipo explícito o por nombre
-				pTypeLower := strings.ToLower(p.Type)
-				pNameLower := strings.ToLower(p.Name)
-				isDateType := pTypeLower == "date"
-				isDateName := strings.Contains(pNameLower, "fecha") || strings.Contains(pNameLower, "periodo")
-
-				if isDateType || isDateName {
-					// Intentar parsear como fecha usando parseDateParam
-					if parsedTime, err := parseDateParam(p.Value); err == nil {
-						args = append(args, parsedTime)
-					} else {
-						// Si falla, loguear y usar valor original
-						log.Printf("[ASYNC_PROCEDURE] Advertencia al parsear fecha en parámetro '%s': %v", p.Name, err)
-						args = append(args, p.Value)
-					}
-				} else {
-					args = append(args, p.Value)
-				}
-			}
-		}
-
-		jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
-			j.Progress = 30
-		})
-
-		// Formatear el nombre para manejar esquema.procedimiento correctamente
-		procedureName := formatObjectName(req.Schema, req.Name)
-
-		call := fmt.Sprintf("BEGIN %s(%s); END;", procedureName, strings.Join(placeholders, ", "))
-
-		stmt, err := db.Prepare(call)
-		if err != nil {
+// runProcedureJob ejecuta el procedimiento de un job con el mismo motor que /procedure
+// ctx se cancela con POST /jobs/{id}/cancel, lo que interrumpe la llamada en Oracle.
+func runProcedureJob(ctx context.Context, jobID string, req ProcedureRequest) {
+	// Capturar panics para evitar que el job quede colgado
+	defer func() {
+		if r := recover(); r != nil {
 			endTime := time.Now()
-			errorMsg := err.Error()
-
-			// Mejorar el mensaje de error para procedimientos no encontrados
-			if strings.Contains(errorMsg, "PLS-00201") {
-				errorMsg = fmt.Sprintf("Procedimiento '%s' no encontrado. Verifica que existe en la base de datos.", req.Name)
-			} else if strings.Contains(errorMsg, "PLS-00306") {
-				errorMsg = fmt.Sprintf("Par├ímetros incorrectos para '%s'. Verifica tipos y cantidad de par├ímetros.", req.Name)
-			}
-
-			jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
+			jobManager.UpdateJob(jobID, func(j *AsyncJob) {
 				j.Status = JobStatusFailed
-				j.Error = errorMsg
+				j.Error = fmt.Sprintf("Panic recuperado: %v", r)
 				j.EndTime = &endTime
 				j.Duration = endTime.Sub(j.StartTime).String()
 				j.Progress = 100
 			})
-			return
+			log.Printf("❌ Panic en job %s: %v", jobID, r)
 		}
-		defer stmt.Close()
-
-		jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
-			j.Progress = 50
-		})
-
-		if _, err := stmt.Exec(args...); err != nil {
-			endTime := time.Now()
-			errorMsg := err.Error()
+	}()
 
-			// Mejorar mensajes de error comunes
-			if strings.Contains(errorMsg, "ORA-06502") {
-				errorMsg = "Error de conversi├│n de tipos. Verifica que los tipos de datos sean correctos."
-			} else if strings.Contains(errorMsg, "ORA-01403") {
-				errorMsg = "No se encontraron datos. El procedimiento no retorn├│ resultados."
-			}
+	// Actualizar estado a running; la duración se mide desde que lo toma un worker
+	attemptStart := time.Now()
+	attempt := 0
+	jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+		j.Status = JobStatusRunning
+		j.StartTime = attemptStart
+		j.Progress = 0
+		j.ProgressMessage = ""
+		j.Attempt++
+		j.NextRetry = nil
+		attempt = j.Attempt
+	})
 
-			jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
-				j.Status = JobStatusFailed
-				j.Error = errorMsg
-				j.EndTime = &endTime
-				j.Duration = endTime.Sub(j.StartTime).String()
-				j.Progress = 100
+	// El avance lo informa el propio procedimiento (API_JOB_PROGRESS o V$SESSION_LONGOPS)
+	result, err := executeProcedure(ctx, &req, &progressTracker{
+		jobID: jobID,
+		report: func(pct int, message string) {
+			jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+				j.Progress = pct
+				j.ProgressMessage = message
 			})
-			return
-		}
+		},
+	})
 
-		jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
-			j.Progress = 80
+	endTime := time.Now()
+	if err != nil && ctx.Err() != nil {
+		jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+			j.Status = JobStatusCancelled
+			j.Error = "Job cancelado durante la ejecución"
+			j.EndTime = &endTime
+			j.Duration = endTime.Sub(j.StartTime).String()
+			j.Progress = 100
 		})
-
-		// Recopilar resultados OUT
-		out := make(map[string]interface{})
-		for i, name := range outIndexes {
-			if datePtr, ok := outDateMap[i]; ok && datePtr != nil {
-				if datePtr.Valid {
-					out[name] = formatDateOutput(datePtr.Time)
-				} else {
-					out[name] = nil
-				}
-				continue
-			}
-			if numPtr, ok := outNumMap[i]; ok && numPtr != nil {
-				if numPtr.Valid {
-					out[name] = numPtr.Float64
-				} else {
-					out[name] = nil
-				}
-				continue
+		log.Printf("[JOBS] Job %s cancelado: %v", jobID, err)
+		return
+	}
+	if err != nil {
+		var failResult map[string]interface{}
+		if perr, ok := err.(*procedureError); ok && (perr.Output != nil || perr.Out != nil) {
+			failResult = map[string]interface{}{}
+			if perr.Out != nil {
+				failResult["out"] = perr.Out
 			}
-			if ptr, ok := outBuffers[i]; ok && ptr != nil {
-				out[name] = *ptr
+			if perr.Output != nil {
+				failResult["dbms_output"] = perr.Output
 			}
 		}
-
-		// Completado exitosamente
-		endTime := time.Now()
-		jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
-			j.Status = JobStatusCompleted
-			j.Result = out
+		var retry *queuedJob
+		var retryIn time.Duration
+		jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+			if j.Retry != nil {
+				j.Attempts = append(j.Attempts, JobAttempt{
+					Attempt:   attempt,
+					StartTime: attemptStart,
+					EndTime:   endTime,
+					Duration:  endTime.Sub(attemptStart).String(),
+					Error:     err.Error(),
+				})
+			}
+			j.Result = failResult
+			j.Error = err.Error()
+			// Error transitorio con intentos disponibles: vuelve a pending hasta el reintento
+			if delay, ok := j.Retry.nextRetry(attempt, err); ok {
+				next := endTime.Add(delay)
+				j.Status = JobStatusPending
+				j.NextRetry = &next
+				j.Progress = 0
+				retry, retryIn = procedureQueuedJob(j, req), delay
+				return
+			}
+			j.Status = JobStatusFailed
 			j.EndTime = &endTime
 			j.Duration = endTime.Sub(j.StartTime).String()
 			j.Progress = 100
 		})
-	}()
+		if retry != nil {
+			jobQueue.EnqueueAfter(retry, retryIn)
+			log.Printf("[JOBS] Job %s: intento %d fallido (%v); reintento en %v", jobID, attempt, err, retryIn)
+		}
+		return
+	}
+
+	// Igual que la respuesta de /procedure: los OUT en "out" y junto a ellos cursores y DBMS_OUTPUT
+	out := map[string]interface{}{"out": result.Out}
+	if len(result.ResultSets) > 0 {
+		out["result_sets"] = result.ResultSets
+		if result.Truncated {
+			out["result_sets_truncated"] = true
+		}
+	}
+	if len(result.Warnings) > 0 {
+		out["warnings"] = result.Warnings
+	}
+	if req.CaptureOutput {
+		out["dbms_output"] = result.Output
+	}
+
+	// Completado exitosamente
+	jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+		j.Status = JobStatusCompleted
+		j.Result = out
+		j.Error = "" // Error de un intento anterior
+		j.EndTime = &endTime
+		j.Duration = endTime.Sub(j.StartTime).String()
+		j.Progress = 100
+	})
 }
 
 // jobsHandler maneja consultas de estado de jobs
@@ -1827,9 +2005,42 @@ func jobsHandler(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/jobs")
 	path = strings.TrimPrefix(path, "/")
 
-	// Si hay un ID, buscar/eliminar ese job espec├¡fico
+	// POST /jobs/{id}/cancel
+	if id, ok := strings.CutSuffix(path, "/cancel"); ok {
+		cancelJobHandler(w, r, id)
+		return
+	}
+
+	// GET /jobs/{id}/events (Server-Sent Events)
+	if id, ok := strings.CutSuffix(path, "/events"); ok {
+		jobEventsHandler(w, r, id)
+		return
+	}
+
+	// GET /jobs/{id}/rows (filas de una consulta de /query/async)
+	if id, ok := strings.CutSuffix(path, "/rows"); ok {
+		jobRowsHandler(w, r, id)
+		return
+	}
+
+	// Si hay un ID, buscar/eliminar ese job específico
 	if path != "" {
 		if r.Method == http.MethodDelete {
+			// Un job de otra instancia se elimina solo cuando terminó: cancelarlo es tarea de su dueña
+			if job, exists := jobManager.Lookup(path); exists {
+				if snap := job.snapshotLocked(); !jobRecovery.owns(&snap) && !snap.Status.isFinal() {
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(map[string]string{
+						"error":    "El job se está ejecutando en otra instancia; cancélalo con POST /jobs/{id}/cancel antes de eliminarlo",
+						"job_id":   path,
+						"instance": snap.Instance,
+					})
+					return
+				}
+			}
+			// Un job pendiente o en ejecución (o los pasos de un workflow) se cancela antes de eliminarlo
+			jobQueue.Cancel(path)
+			workflowManager.Cancel(path)
 			err := jobManager.DeleteJob(path)
 			if err != nil {
 				w.WriteHeader(http.StatusNotFound)
@@ -1843,18 +2054,35 @@ func jobsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// GET de job espec├¡fico
-		job, exists := jobManager.GetJob(path)
+		// GET de job específico; con ?wait=30s espera a que cambie o termine (long polling)
+		if waitParam := r.URL.Query().Get("wait"); waitParam != "" {
+			wait, err := parseJobWait(waitParam)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			job, changed, ok := waitForJobChange(r, path, wait)
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Job no encontrado"})
+				return
+			}
+			w.Header().Set("X-Job-Changed", strconv.FormatBool(changed))
+			json.NewEncoder(w).Encode(job)
+			return
+		}
+		job, exists := jobManager.Lookup(path)
 		if !exists {
 			w.WriteHeader(http.StatusNotFound)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Job no encontrado"})
 			return
 		}
-		json.NewEncoder(w).Encode(job)
+		json.NewEncoder(w).Encode(job.snapshotLocked())
 		return
 	}
 
-	// Sin ID - Listar o eliminar m├║ltiples jobs
+	// Sin ID - Listar o eliminar múltiples jobs
 	if r.Method == http.MethodDelete {
 		// DELETE /jobs?status=completed,failed&older_than=7
 		queryParams := r.URL.Query()
@@ -1873,7 +2101,7 @@ func jobsHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		// Validar que al menos un filtro est├® presente
+		// Validar que al menos un filtro esté presente
 		if len(statusFilter) == 0 && olderThan == 0 {
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -1882,6 +2110,7 @@ func jobsHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		jobManager.syncFromDB()
 		count, err := jobManager.DeleteJobs(statusFilter, olderThan)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -1903,11 +2132,118 @@ func jobsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	jobs := jobManager.GetAllJobs()
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"total": len(jobs),
-		"jobs":  jobs,
-	})
+	listJobsHandler(w, r)
+}
+
+// cancelJobHandler cancela un job (POST /jobs/{id}/cancel), ver cancelJob
+func cancelJobHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite POST"})
+		return
+	}
+	status, body := cancelJob(id)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// cancelJob cancela un job: si está pendiente se quita de la cola y si está en ejecución
+// se cancela su contexto, lo que interrumpe la llamada en Oracle. Si el job es de otra
+// instancia se le pide la cancelación a través de ASYNC_JOBS (ver cluster.go).
+// Retorna el código HTTP y el cuerpo de la respuesta.
+func cancelJob(id string) (int, map[string]string) {
+	job, exists := jobManager.Lookup(id)
+	if !exists {
+		return http.StatusNotFound, map[string]string{"error": "Job no encontrado"}
+	}
+	snap := job.snapshotLocked()
+
+	// Job de otra instancia (o pendiente en la cola compartida)
+	if jobCluster.shared() && !jobRecovery.owns(&snap) && !snap.Status.isFinal() {
+		if snap.Instance == "" && snap.Status == JobStatusPending && jobCluster.claimUnclaimed(id) {
+			endTime := time.Now()
+			jobManager.UpdateJob(id, func(j *AsyncJob) {
+				j.Status = JobStatusCancelled
+				j.Error = "Job cancelado antes de ejecutarse"
+				j.EndTime = &endTime
+				j.Progress = 100
+			})
+			log.Printf("[JOBS] Job %s cancelado en la cola compartida", id)
+			return http.StatusOK, map[string]string{
+				"message": "Job cancelado",
+				"job_id":  id,
+				"status":  string(JobStatusCancelled),
+			}
+		}
+		// Si otra instancia lo tomó mientras tanto, la cancelación le llega a esa instancia
+		if jobCluster.requestCancel(id) {
+			instance := snap.Instance
+			if instance == "" {
+				instance = "que lo tomó"
+			}
+			return http.StatusAccepted, map[string]string{
+				"message":          fmt.Sprintf("Cancelación enviada a la instancia %s; el job pasará a cancelled cuando la procese", instance),
+				"job_id":           id,
+				"instance":         snap.Instance,
+				"status":           string(snap.Status),
+				"check_status_url": fmt.Sprintf("/jobs/%s", id),
+			}
+		}
+		// Terminó mientras tanto
+		jobManager.refreshFromDB(id)
+		if current, ok := jobManager.GetJob(id); ok {
+			snap = current.snapshotLocked()
+		}
+		return http.StatusConflict, map[string]string{
+			"error":  fmt.Sprintf("El job no se puede cancelar (estado: %s)", snap.Status),
+			"job_id": id,
+		}
+	}
+
+	// Workflow: se cancelan sus pasos (ver workflow.go)
+	if snap.Workflow != nil && !snap.Status.isFinal() {
+		status, message := http.StatusOK, "Workflow cancelado"
+		if workflowManager.Cancel(id) {
+			status, message = http.StatusAccepted, "Cancelación solicitada; el workflow pasará a cancelled al interrumpirse los pasos en ejecución"
+		}
+		return status, map[string]string{
+			"message":          message,
+			"job_id":           id,
+			"check_status_url": fmt.Sprintf("/jobs/%s", id),
+		}
+	}
+
+	wasPending, found := jobQueue.Cancel(id)
+	if !found {
+		return http.StatusConflict, map[string]string{
+			"error":  fmt.Sprintf("El job no se puede cancelar (estado: %s)", snap.Status),
+			"job_id": id,
+		}
+	}
+
+	if wasPending {
+		endTime := time.Now()
+		jobManager.UpdateJob(id, func(j *AsyncJob) {
+			j.Status = JobStatusCancelled
+			j.Error = "Job cancelado antes de ejecutarse"
+			j.EndTime = &endTime
+			j.Progress = 100
+		})
+		log.Printf("[JOBS] Job %s cancelado en cola", id)
+		return http.StatusOK, map[string]string{
+			"message": "Job cancelado",
+			"job_id":  id,
+			"status":  string(JobStatusCancelled),
+		}
+	}
+
+	// En ejecución: el worker registra el estado cancelled cuando Oracle interrumpe la llamada
+	return http.StatusAccepted, map[string]string{
+		"message":          "Cancelación solicitada; el job pasará a cancelled al interrumpirse la llamada",
+		"job_id":           id,
+		"status":           string(JobStatusRunning),
+		"check_status_url": fmt.Sprintf("/jobs/%s", id),
+	}
 }
 
 func pingHandler(w http.ResponseWriter, r *http.Request) {
@@ -1943,7 +2279,7 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inv├ílido"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
 		return
 	}
 	if strings.TrimSpace(req.Query) == "" {
@@ -1952,11 +2288,11 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Normalizar saltos de l├¡nea: reemplazar \r\n y \n por salto de l├¡nea real
+	// Normalizar saltos de línea: reemplazar \r\n y \n por salto de línea real
 	normalizedQuery := strings.ReplaceAll(req.Query, "\r\n", "\n")
 	normalizedQuery = strings.ReplaceAll(normalizedQuery, "\\n", "\n")
 
-	// Detectar si es una consulta de log (evitar recursi├│n)
+	// Detectar si es una consulta de log (evitar recursión)
 	upperQuery := strings.ToUpper(normalizedQuery)
 	isLogQuery := strings.Contains(upperQuery, "FROM QUERY_LOG") ||
 		strings.Contains(upperQuery, "FROM ASYNC_JOBS") ||
@@ -1992,7 +2328,7 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	cols, err := rows.Columns()
+	results, err := scanRowMaps(rows, 0)
 	if err != nil {
 		if qlog != nil {
 			qlog.Success = false
@@ -2006,38 +2342,6 @@ func queryHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	results := []map[string]interface{}{}
-	for rows.Next() {
-		columns := make([]interface{}, len(cols))
-		columnPointers := make([]interface{}, len(cols))
-		for i := range columns {
-			columnPointers[i] = &columns[i]
-		}
-		if err := rows.Scan(columnPointers...); err != nil {
-			if qlog != nil {
-				qlog.Success = false
-				qlog.ErrorMsg = err.Error()
-				qlog.Duration = time.Since(startExec).String()
-				go saveQueryLog(qlog)
-			}
-
-			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
-			return
-		}
-		rowMap := make(map[string]interface{})
-		for i, colName := range cols {
-			val := columns[i]
-			b, ok := val.([]byte)
-			if ok {
-				rowMap[colName] = string(b)
-			} else {
-				rowMap[colName] = val
-			}
-		}
-		results = append(results, rowMap)
-	}
-
 	// Registro exitoso
 	if qlog != nil {
 		qlog.Success = true
@@ -2082,7 +2386,7 @@ func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		enableCORS(&w, r)
 
-		// Permitir peticiones OPTIONS (preflight) sin autenticaci├│n
+		// Permitir peticiones OPTIONS (preflight) sin autenticación
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -2134,11 +2438,12 @@ func execHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Query string `json:"query"`
+		Query         string `json:"query"`
+		CaptureOutput bool   `json:"capture_output,omitempty"` // Retornar las líneas de DBMS_OUTPUT
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inv├ílido"})
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
 		return
 	}
 	if req.Query == "" {
@@ -2159,7 +2464,7 @@ func execHandler(w http.ResponseWriter, r *http.Request) {
 		UserIP:        r.RemoteAddr,
 	}
 
-	// Detectar si es un comando de modificaci├│n
+	// Detectar si es un comando de modificación
 	q := req.Query
 	qType := ""
 	if len(q) > 0 {
@@ -2172,11 +2477,40 @@ func execHandler(w http.ResponseWriter, r *http.Request) {
 			qType = "exec"
 		} else if len(q) >= 6 && (q[:6] == "DELETE" || q[:6] == "delete") {
 			qType = "exec"
+		} else if upper := strings.ToUpper(q); strings.HasPrefix(upper, "BEGIN") || strings.HasPrefix(upper, "DECLARE") {
+			// Bloques PL/SQL anónimos (p.ej. con DBMS_OUTPUT.PUT_LINE)
+			qType = "exec"
 		}
 	}
 
+	// Con capture_output se usa una conexión dedicada con DBMS_OUTPUT habilitado
+	ctx := r.Context()
+	var outConn *sql.Conn
+	if req.CaptureOutput {
+		conn, err := beginOutputCapture(ctx)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		outConn = conn
+		defer outConn.Close()
+	}
+
+	// withOutput agrega las líneas de DBMS_OUTPUT a la respuesta cuando se pidieron
+	withOutput := func(resp map[string]interface{}) map[string]interface{} {
+		if outConn != nil {
+			lines, err := readDBMSOutput(ctx, outConn)
+			if err != nil {
+				log.Printf("[EXEC] Advertencia al leer DBMS_OUTPUT: %v", err)
+			}
+			resp["dbms_output"] = lines
+		}
+		return resp
+	}
+
 	if qType == "exec" {
-		res, err := db.Exec(req.Query)
+		res, err := execOn(ctx, outConn, req.Query)
 		if err != nil {
 			qlog.Success = false
 			qlog.ErrorMsg = err.Error()
@@ -2184,12 +2518,12 @@ func execHandler(w http.ResponseWriter, r *http.Request) {
 			go saveQueryLog(qlog)
 
 			w.WriteHeader(http.StatusInternalServerError)
-			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			json.NewEncoder(w).Encode(withOutput(map[string]interface{}{"error": err.Error()}))
 			return
 		}
 		rowsAffected, err := res.RowsAffected()
 		if err != nil {
-			log.Printf("ÔÜá´©Å  No se pudo obtener rows affected: %v", err)
+			log.Printf("⚠️  No se pudo obtener rows affected: %v", err)
 			rowsAffected = 0
 		}
 
@@ -2198,11 +2532,11 @@ func execHandler(w http.ResponseWriter, r *http.Request) {
 		qlog.Duration = time.Since(startExec).String()
 		go saveQueryLog(qlog)
 
-		json.NewEncoder(w).Encode(map[string]interface{}{"rows_affected": rowsAffected})
+		json.NewEncoder(w).Encode(withOutput(map[string]interface{}{"rows_affected": rowsAffected}))
 		return
 	}
 
-	rows, err := db.Query(req.Query)
+	rows, err := queryOn(ctx, outConn, req.Query)
 	if err != nil {
 		qlog.Success = false
 		qlog.ErrorMsg = err.Error()
@@ -2210,7 +2544,7 @@ func execHandler(w http.ResponseWriter, r *http.Request) {
 		go saveQueryLog(qlog)
 
 		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		json.NewEncoder(w).Encode(withOutput(map[string]interface{}{"error": err.Error()}))
 		return
 	}
 	defer rows.Close()
@@ -2264,6 +2598,12 @@ func execHandler(w http.ResponseWriter, r *http.Request) {
 	qlog.Duration = time.Since(startExec).String()
 	go saveQueryLog(qlog)
 
+	if outConn != nil {
+		// Con capture_output la respuesta pasa a ser un objeto para incluir la salida
+		rows.Close()
+		json.NewEncoder(w).Encode(withOutput(map[string]interface{}{"results": results}))
+		return
+	}
 	json.NewEncoder(w).Encode(results)
 }
 
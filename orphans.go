@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Valores por defecto de la detección de jobs huérfanos
+const (
+	defaultJobHeartbeatInterval = 30 * time.Second
+	defaultJobOrphanTimeout     = 2 * time.Minute
+	defaultInterruptMaxAttempts = 3 // Ejecuciones de un job con on_interrupt=requeue sin política retry
+)
+
+// Políticas para un job que estaba en ejecución cuando su instancia se detuvo ("on_interrupt")
+const (
+	OnInterruptFail    = "fail"    // Queda interrupted (por defecto): el procedimiento pudo ejecutarse en parte
+	OnInterruptRequeue = "requeue" // Se vuelve a encolar; solo para procedimientos que se pueden repetir
+)
+
+// JobRecovery registra qué instancia es dueña de cada job (INSTANCE_NAME) y mantiene su
+// heartbeat, para que al reiniciar se detecten los jobs que quedaron sin nadie que los ejecute
+type JobRecovery struct {
+	owner         string        // Instancia de este proceso
+	interval      time.Duration // Cada cuánto se actualiza HEARTBEAT de los jobs propios
+	orphanTimeout time.Duration // Sin heartbeat por más de esto, la instancia dueña se considera detenida
+}
+
+var jobRecovery = &JobRecovery{
+	interval:      defaultJobHeartbeatInterval,
+	orphanTimeout: defaultJobOrphanTimeout,
+}
+
+// newJobRecoveryFromEnv arma la configuración con ASYNC_HEARTBEAT_INTERVAL y ASYNC_ORPHAN_TIMEOUT.
+// owner es el nombre de la instancia; debe ser único entre las instancias que comparten ASYNC_JOBS.
+func newJobRecoveryFromEnv(owner string) (*JobRecovery, error) {
+	r := &JobRecovery{
+		owner:         owner,
+		interval:      defaultJobHeartbeatInterval,
+		orphanTimeout: defaultJobOrphanTimeout,
+	}
+	for name, dest := range map[string]*time.Duration{
+		"ASYNC_HEARTBEAT_INTERVAL": &r.interval,
+		"ASYNC_ORPHAN_TIMEOUT":     &r.orphanTimeout,
+	} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%s debe ser una duración positiva (p.ej. 30s, 2m)", name)
+		}
+		*dest = d
+	}
+	if r.orphanTimeout <= r.interval {
+		return nil, fmt.Errorf("ASYNC_ORPHAN_TIMEOUT (%v) debe ser mayor que ASYNC_HEARTBEAT_INTERVAL (%v)", r.orphanTimeout, r.interval)
+	}
+	return r, nil
+}
+
+// jobOwnerName retorna el nombre con que la instancia figura como dueña de sus jobs:
+// el nombre de instancia configurado o, si es "auto", host:puerto
+func jobOwnerName(instance, port string) string {
+	if instance != "" && strings.ToLower(instance) != "auto" {
+		return instance
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return host + ":" + port
+}
+
+// parseOnInterrupt valida "on_interrupt" (vacío = fail)
+func parseOnInterrupt(value string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", OnInterruptFail:
+		return OnInterruptFail, nil
+	case OnInterruptRequeue:
+		return OnInterruptRequeue, nil
+	}
+	return "", fmt.Errorf("'on_interrupt' debe ser '%s' o '%s'", OnInterruptFail, OnInterruptRequeue)
+}
+
+// owns indica si el job pertenece a esta instancia
+func (r *JobRecovery) owns(job *AsyncJob) bool {
+	return job.Instance == r.owner
+}
+
+// orphaned indica si un job sin terminar quedó sin instancia que lo ejecute: al iniciar, los de
+// esta misma instancia; los que estaban en ejecución sin dueño registrado (o pendientes, sin cola
+// compartida); y los de otra instancia que dejó de actualizar el heartbeat
+func (r *JobRecovery) orphaned(job *AsyncJob, now time.Time, startup bool) bool {
+	switch job.Instance {
+	case r.owner:
+		return startup
+	case "":
+		// Un pendiente sin dueño está en la cola compartida (ver cluster.go)
+		return job.Status == JobStatusRunning || job.Workflow != nil || !jobCluster.shared()
+	}
+	return job.Heartbeat == nil || now.Sub(*job.Heartbeat) > r.orphanTimeout
+}
+
+// RecoverOrphanedJobs toma los jobs huérfanos: al iniciar, entre los cargados de ASYNC_JOBS, y
+// después periódicamente los de instancias que se detuvieron (ver JobCluster.Start). Los
+// pendientes vuelven a la cola compartida (o se adoptan sin ella, ver requeuePendingJobs) y los
+// workflows se adoptan (resumeWorkflows); los que estaban en ejecución quedan interrupted o se
+// reencolan según su on_interrupt. Los jobs de instancias activas no se tocan.
+func (r *JobRecovery) RecoverOrphanedJobs(startup bool) {
+	now := time.Now()
+	shared := jobCluster.shared()
+	adopted, requeued, interrupted := 0, 0, 0
+	for _, job := range jobManager.GetAllJobs() {
+		snap := job.snapshotLocked()
+		if snap.Status.isFinal() || !r.orphaned(&snap, now, startup) {
+			continue
+		}
+
+		previous := snap.Instance
+		if previous == "" {
+			previous = "desconocida"
+		}
+		lastSeen := "sin heartbeat"
+		if snap.Heartbeat != nil {
+			lastSeen = "último heartbeat " + snap.Heartbeat.Format(time.RFC3339)
+		}
+
+		if snap.Status != JobStatusRunning || snap.Workflow != nil {
+			target := r.owner
+			if shared && snap.Workflow == nil {
+				target = "" // Lo toma cualquier instancia con workers libres
+			}
+			if !jobCluster.transfer(&snap, target, now) {
+				continue
+			}
+			jobManager.UpdateJob(snap.ID, func(j *AsyncJob) {
+				j.Instance = target
+				j.Heartbeat = &now
+			})
+			adopted++
+			continue
+		}
+
+		maxAttempts := defaultInterruptMaxAttempts
+		if snap.Retry != nil {
+			maxAttempts = snap.Retry.MaxAttempts
+		}
+		requeue := snap.OnInterrupt == OnInterruptRequeue && snap.Attempt < maxAttempts
+		target := r.owner
+		if requeue && shared {
+			target = ""
+		}
+		if !jobCluster.transfer(&snap, target, now) {
+			continue
+		}
+
+		reason := fmt.Sprintf("La instancia %s se detuvo mientras el job estaba en ejecución (%s)", previous, lastSeen)
+		jobManager.UpdateJob(snap.ID, func(j *AsyncJob) {
+			j.Instance = target
+			j.Heartbeat = &now
+			if requeue {
+				end := now
+				if snap.Heartbeat != nil {
+					end = *snap.Heartbeat
+				}
+				j.Attempts = append(j.Attempts, JobAttempt{
+					Attempt:   j.Attempt,
+					StartTime: j.StartTime,
+					EndTime:   end,
+					Duration:  end.Sub(j.StartTime).String(),
+					Error:     reason,
+				})
+				j.Status = JobStatusPending
+				j.Error = reason
+				j.Progress = 0
+				return
+			}
+			if j.OnInterrupt == OnInterruptRequeue {
+				reason += fmt.Sprintf("; se agotaron los %d intentos", maxAttempts)
+			} else {
+				reason += "; el procedimiento pudo haberse ejecutado parcialmente"
+			}
+			j.Status = JobStatusInterrupted
+			j.Error = reason
+			j.EndTime = &now
+			j.Duration = now.Sub(j.StartTime).String()
+			j.Progress = 100
+		})
+		if requeue {
+			requeued++
+			log.Printf("[JOBS] Job %s huérfano (instancia %s) reencolado", snap.ID, previous)
+		} else {
+			interrupted++
+			log.Printf("[JOBS] Job %s huérfano (instancia %s) marcado interrupted", snap.ID, previous)
+		}
+	}
+	if adopted+requeued+interrupted > 0 {
+		log.Printf("[JOBS] Jobs huérfanos recuperados: %d pendientes o workflows reasignados, %d reencolados, %d interrumpidos",
+			adopted, requeued, interrupted)
+	}
+	if requeued+adopted > 0 && shared {
+		jobCluster.Wake()
+	}
+}
+
+// Start actualiza periódicamente HEARTBEAT de los jobs sin terminar de esta instancia
+func (r *JobRecovery) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			r.beat()
+		}
+	}()
+}
+
+// beat registra el heartbeat en memoria y en ASYNC_JOBS
+func (r *JobRecovery) beat() {
+	now := time.Now()
+	jobManager.mu.Lock()
+	for _, job := range jobManager.jobs {
+		if r.owns(job) && !job.Status.isFinal() {
+			job.Heartbeat = &now
+		}
+	}
+	jobManager.mu.Unlock()
+
+	if db == nil {
+		return
+	}
+	_, err := db.Exec(`UPDATE ASYNC_JOBS SET HEARTBEAT = :1
+		WHERE INSTANCE_NAME = :2 AND STATUS IN ('pending', 'running')`, now, r.owner)
+	if err != nil {
+		log.Printf("[JOBS] Error actualizando heartbeat de la instancia %s: %v", r.owner, err)
+	}
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// paramProblem es un error de validación de un parámetro contra la firma real del programa
+type paramProblem struct {
+	Param   string `json:"param,omitempty"`
+	Code    string `json:"code"` // missing, unknown, direction, type, date, too_long, kind
+	Message string `json:"message"`
+}
+
+// overloadMatch es el resultado de mapear los parámetros del request a una sobrecarga
+type overloadMatch struct {
+	overload *ProgramOverload
+	args     []*ProgramArgument // Argumento Oracle de cada parámetro del request (el retorno en funciones)
+	byName   bool               // Los parámetros se mapearon por nombre
+	ordered  bool               // El mapeo coincide con el orden declarado (la llamada posicional sirve)
+	exact    int                // Cantidad de parámetros cuyo tipo coincide exactamente
+	problems []paramProblem
+}
+
+// describeOverload arma una firma corta para mensajes de error: "2: (P_ID NUMBER, P_FECHA DATE)"
+func describeOverload(ov *ProgramOverload) string {
+	args := []string{}
+	for _, a := range ov.Params {
+		args = append(args, fmt.Sprintf("%s %s", a.Name, a.DataType))
+	}
+	desc := fmt.Sprintf("%d: (%s)", ov.Overload, strings.Join(args, ", "))
+	if ov.Return != nil {
+		desc += " RETURN " + ov.Return.DataType
+	}
+	return desc
+}
+
+// describeOverloads lista las sobrecargas disponibles separadas por "; "
+func describeOverloads(sig *ProgramSignature) string {
+	descs := []string{}
+	for i := range sig.Overloads {
+		descs = append(descs, describeOverload(&sig.Overloads[i]))
+	}
+	return strings.Join(descs, "; ")
+}
+
+// isDateLikeType indica si el tipo de /procedure es una fecha u hora
+func isDateLikeType(t string) bool {
+	return t == "date" || t == "timestamp" || t == "timestamptz"
+}
+
+// checkParamType compara un parámetro del request con un argumento Oracle.
+// Retorna el problema encontrado (nil si es compatible) y si el tipo coincide exactamente.
+func checkParamType(p ProcedureParam, arg *ProgramArgument) (*paramProblem, bool) {
+	typeError := func(format string, a ...interface{}) (*paramProblem, bool) {
+		return &paramProblem{Param: p.Name, Code: "type", Message: fmt.Sprintf(format, a...)}, false
+	}
+
+	if p.TypeName != "" {
+		if arg.TypeName == "" {
+			return typeError("'type_name' %s indicado, pero el argumento es %s", p.TypeName, arg.DataType)
+		}
+		want := strings.ToUpper(p.TypeName)
+		have := strings.ToUpper(arg.TypeName)
+		if have != want && !strings.HasSuffix(have, "."+want) {
+			return typeError("se esperaba 'type_name' %s", arg.TypeName)
+		}
+		return nil, true
+	}
+
+	if p.Type != "" {
+		pType := strings.ToLower(p.Type)
+		if pType == "integer" || pType == "float" {
+			pType = "number"
+		}
+		// date, timestamp y timestamptz son intercambiables (Oracle convierte entre ellos)
+		dateLike := isDateLikeType(pType) && isDateLikeType(arg.Type)
+		if pType != arg.Type && !dateLike {
+			if arg.Type == "" {
+				return typeError("el argumento es %s: indica 'type_name' %s", arg.DataType, arg.TypeName)
+			}
+			return typeError("tipo '%s' indicado, pero el argumento es %s", p.Type, arg.DataType)
+		}
+	}
+
+	// Los OUT no traen valor: solo se compara el tipo declarado
+	if p.IsOut() || p.Value == nil {
+		return nil, p.Type != ""
+	}
+
+	switch v := p.Value.(type) {
+	case bool:
+		if arg.Type != "boolean" {
+			return typeError("valor booleano, pero el argumento es %s", arg.DataType)
+		}
+		return nil, true
+	case float64:
+		switch arg.Type {
+		case "number":
+			return nil, true
+		case "string":
+			return nil, false
+		case "boolean":
+			if v == 0 || v == 1 {
+				return nil, false
+			}
+		}
+		return typeError("valor numérico, pero el argumento es %s", arg.DataType)
+	case string:
+		switch arg.Type {
+		case "string":
+			if arg.Length > 0 && strings.Contains(arg.DataType, "CHAR") &&
+				utf8.RuneCountInString(v) > arg.Length {
+				return &paramProblem{Param: p.Name, Code: "too_long", Message: fmt.Sprintf(
+					"el valor tiene %d caracteres y el máximo es %d", utf8.RuneCountInString(v), arg.Length)}, false
+			}
+			return nil, true
+		case "number":
+			if _, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err != nil {
+				return typeError("'%s' no es un número válido para %s", v, arg.DataType)
+			}
+			return nil, false
+		case "date":
+			if _, err := parseDateParam(v); err != nil {
+				// Una fecha con hora ISO-8601 también se acepta (Oracle trunca a DATE)
+				if _, err := parseTimestampParam(v); err != nil {
+					return &paramProblem{Param: p.Name, Code: "date", Message: fmt.Sprintf(
+						"no se pudo interpretar '%s' como fecha (formatos: %s)", v, strings.Join(getDateInputFormats(), ", "))}, false
+				}
+			}
+			return nil, true
+		case "timestamp", "timestamptz":
+			if _, err := parseTimestampParam(v); err != nil {
+				return &paramProblem{Param: p.Name, Code: "date", Message: fmt.Sprintf("'%s': %v", v, err)}, false
+			}
+			return nil, true
+		case "interval":
+			_, yearMonth, err := parseIntervalParam(v)
+			if err != nil {
+				return typeError("%v", err)
+			}
+			if yearMonth != isYearMonthInterval(arg.DataType) {
+				return typeError("la duración '%s' no corresponde a %s", v, arg.DataType)
+			}
+			return nil, true
+		case "boolean":
+			if _, err := parseBoolParam(v); err != nil {
+				return typeError("'%s' no es un booleano válido", v)
+			}
+			return nil, false
+		}
+		return typeError("el argumento es %s: indica 'type_name' %s", arg.DataType, arg.TypeName)
+	}
+	// Arrays y objetos (siempre con type_name, ver validateProcedureRequest)
+	if arg.Type != "" {
+		return typeError("array u objeto, pero el argumento es %s", arg.DataType)
+	}
+	return nil, false
+}
+
+// matchOverload mapea los parámetros del request a una sobrecarga y junta los problemas encontrados.
+// Se mapea por nombre si algún nombre coincide con un argumento; si ninguno coincide, por posición.
+func matchOverload(req *ProcedureRequest, ov *ProgramOverload) *overloadMatch {
+	m := &overloadMatch{overload: ov, args: make([]*ProgramArgument, len(req.Params)), ordered: true}
+	addProblem := func(param, code, format string, a ...interface{}) {
+		m.problems = append(m.problems, paramProblem{Param: param, Code: code, Message: fmt.Sprintf(format, a...)})
+	}
+
+	retIndex := -1
+	if req.IsFunction {
+		retIndex = returnParamIndex(req.Params)
+	}
+	if req.IsFunction && ov.Type != "FUNCTION" {
+		addProblem("", "kind", "es un procedimiento: quita 'isFunction'")
+		return m
+	}
+	if !req.IsFunction && ov.Type == "FUNCTION" {
+		addProblem("", "kind", "es una función: indica \"isFunction\": true y un parámetro OUT para el retorno")
+		return m
+	}
+	if retIndex != -1 {
+		m.args[retIndex] = ov.Return
+		if problem, exact := checkParamType(req.Params[retIndex], ov.Return); problem != nil {
+			m.problems = append(m.problems, *problem)
+		} else if exact {
+			m.exact++
+		}
+	}
+
+	byName := make(map[string]int, len(ov.Params))
+	for i, a := range ov.Params {
+		byName[strings.ToUpper(a.Name)] = i
+	}
+	for i, p := range req.Params {
+		if i == retIndex {
+			continue
+		}
+		if _, ok := byName[strings.ToUpper(strings.TrimSpace(p.Name))]; ok {
+			m.byName = true
+			break
+		}
+	}
+
+	used := make(map[int]bool, len(ov.Params))
+	pos := 0
+	for i, p := range req.Params {
+		if i == retIndex {
+			continue
+		}
+		argIdx := pos
+		pos++
+		if m.byName {
+			idx, ok := byName[strings.ToUpper(strings.TrimSpace(p.Name))]
+			if !ok {
+				addProblem(p.Name, "unknown", "el parámetro no existe en el programa")
+				continue
+			}
+			if idx != argIdx {
+				m.ordered = false
+			}
+			if used[idx] {
+				addProblem(p.Name, "unknown", "el parámetro está repetido")
+				continue
+			}
+			argIdx = idx
+		} else if argIdx >= len(ov.Params) {
+			addProblem(p.Name, "unknown", "sobra: el programa recibe %d parámetros", len(ov.Params))
+			continue
+		}
+		arg := &ov.Params[argIdx]
+		used[argIdx] = true
+		m.args[i] = arg
+
+		// Dirección: un OUT necesita un argumento OUT o IN/OUT y viceversa
+		argIsOut := arg.Direction == "OUT" || arg.Direction == "IN/OUT"
+		argIsIn := arg.Direction == "IN" || arg.Direction == "IN/OUT"
+		if p.IsOut() && !argIsOut {
+			addProblem(p.Name, "direction", "el argumento %s es de entrada (IN)", arg.Name)
+			continue
+		}
+		if !p.IsOut() && !argIsIn {
+			addProblem(p.Name, "direction", "el argumento %s es de salida: indica \"direction\": \"OUT\"", arg.Name)
+			continue
+		}
+		if problem, exact := checkParamType(p, arg); problem != nil {
+			m.problems = append(m.problems, *problem)
+		} else if exact {
+			m.exact++
+		}
+	}
+
+	// Los argumentos sin valor por defecto son obligatorios; saltear uno obliga a la notación con nombre
+	for i, a := range ov.Params {
+		if used[i] {
+			continue
+		}
+		if !a.Defaulted {
+			addProblem(a.Name, "missing", "falta el parámetro obligatorio %s (%s %s)", a.Name, a.Direction, a.DataType)
+		} else if i < pos {
+			m.ordered = false
+		}
+	}
+	return m
+}
+
+// apply deja en el request lo resuelto: tipos tomados de la firma y, si hace falta, los nombres
+// de argumento para invocar con notación con nombre.
+func (m *overloadMatch) apply(req *ProcedureRequest, named bool) {
+	for i := range req.Params {
+		p := &req.Params[i]
+		arg := m.args[i]
+		if arg == nil {
+			continue
+		}
+		// Sin tipo explícito se usa el declarado (evita adivinar por el nombre del parámetro)
+		if p.Type == "" && p.TypeName == "" && arg.Type != "" {
+			p.Type = arg.Type
+		}
+		p.oracleType = arg.DataType
+	}
+	if !named {
+		return
+	}
+	req.argNames = make([]string, len(req.Params))
+	for i, arg := range m.args {
+		if arg != nil && arg.Position > 0 {
+			req.argNames[i] = arg.Name
+		}
+	}
+}
+
+// resolveOverload valida los parámetros contra la firma real (ALL_ARGUMENTS) y, si el programa
+// está sobrecargado (o se indicó "overload"), elige la sobrecarga a invocar con notación con nombre.
+// Si la firma no se puede leer se mantiene la llamada posicional sin validar, como siempre.
+// Si la firma cacheada rechaza los parámetros, se vuelve a leer por si el programa cambió.
+func resolveOverload(ctx context.Context, req *ProcedureRequest) error {
+	sig, cached, err := cachedSignature(ctx, req.Schema, req.Name)
+	if err == nil && sig != nil && cached {
+		if err := matchSignature(req, sig); err == nil {
+			req.signatureCached = true
+			return nil
+		}
+		invalidateSignature(req.Schema, req.Name)
+		sig, _, err = cachedSignature(ctx, req.Schema, req.Name)
+	}
+	if err != nil {
+		if req.Overload > 0 {
+			return &procedureError{Status: http.StatusInternalServerError, Msg: fmt.Sprintf("No se pudo leer la firma de '%s': %v", req.Name, err)}
+		}
+		log.Printf("[PROCEDURE] Advertencia: no se pudo leer la firma de '%s': %v", req.Name, err)
+		return nil
+	}
+	if sig == nil {
+		if req.Overload > 0 {
+			return &procedureError{Status: http.StatusNotFound, Msg: fmt.Sprintf("Programa '%s' no encontrado", req.Name)}
+		}
+		return nil
+	}
+	return matchSignature(req, sig)
+}
+
+// matchSignature valida los parámetros contra la firma y aplica la sobrecarga elegida al request.
+// Si retorna error el request no se modificó.
+func matchSignature(req *ProcedureRequest, sig *ProgramSignature) error {
+	// Una sola firma posible: reportar cada problema por campo
+	if req.Overload > 0 || len(sig.Overloads) == 1 {
+		var ov *ProgramOverload
+		for i := range sig.Overloads {
+			n := sig.Overloads[i].Overload
+			single := len(sig.Overloads) == 1 && (req.Overload == 0 || (n == 0 && req.Overload == 1))
+			if single || n == req.Overload {
+				ov = &sig.Overloads[i]
+				break
+			}
+		}
+		if ov == nil {
+			return &procedureError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(
+				"La sobrecarga %d no existe para '%s'. Disponibles: %s", req.Overload, sig.FullName, describeOverloads(sig))}
+		}
+		m := matchOverload(req, ov)
+		if len(m.problems) > 0 {
+			return &procedureError{Status: http.StatusBadRequest,
+				Msg:     fmt.Sprintf("Parámetros inválidos para '%s'", sig.FullName),
+				Details: m.problems}
+		}
+		m.apply(req, len(sig.Overloads) > 1 || !m.ordered)
+		return nil
+	}
+
+	matches := []*overloadMatch{}
+	for i := range sig.Overloads {
+		if m := matchOverload(req, &sig.Overloads[i]); len(m.problems) == 0 {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return &procedureError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(
+			"Ninguna sobrecarga de '%s' coincide con los parámetros enviados. Disponibles: %s", sig.FullName, describeOverloads(sig))}
+	}
+
+	// Desempatar por cantidad de tipos que coinciden exactamente
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].exact > matches[j].exact })
+	if len(matches) > 1 && matches[0].exact == matches[1].exact {
+		tied := []string{}
+		for _, m := range matches {
+			if m.exact == matches[0].exact {
+				tied = append(tied, describeOverload(m.overload))
+			}
+		}
+		return &procedureError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(
+			"Llamada ambigua a '%s': los parámetros coinciden con las sobrecargas %s. Indica 'overload' o el 'type' de los parámetros",
+			sig.FullName, strings.Join(tied, "; "))}
+	}
+
+	log.Printf("[PROCEDURE] Sobrecarga elegida para %s: %s", sig.FullName, describeOverload(matches[0].overload))
+	matches[0].apply(req, true)
+	return nil
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSignature es PKG.REGISTRAR con dos sobrecargas:
+// 1: (P_ID NUMBER, P_NOMBRE VARCHAR2(10) DEFAULT, P_RESULT OUT VARCHAR2)
+// 2: (P_FECHA DATE, P_RESULT OUT VARCHAR2)
+func testSignature() *ProgramSignature {
+	result := ProgramArgument{Name: "P_RESULT", Position: 3, Direction: "OUT", DataType: "VARCHAR2", Type: "string"}
+	return &ProgramSignature{
+		Schema: "APP", Package: "PKG", Name: "REGISTRAR", FullName: "APP.PKG.REGISTRAR",
+		Overloads: []ProgramOverload{
+			{Overload: 1, Type: "PROCEDURE", Params: []ProgramArgument{
+				{Name: "P_ID", Position: 1, Direction: "IN", DataType: "NUMBER", Type: "number"},
+				{Name: "P_NOMBRE", Position: 2, Direction: "IN", DataType: "VARCHAR2", Type: "string", Length: 10, Defaulted: true},
+				result,
+			}},
+			{Overload: 2, Type: "PROCEDURE", Params: []ProgramArgument{
+				{Name: "P_FECHA", Position: 1, Direction: "IN", DataType: "DATE", Type: "date"},
+				{Name: "P_RESULT", Position: 2, Direction: "OUT", DataType: "VARCHAR2", Type: "string"},
+			}},
+		},
+	}
+}
+
+func problemCodes(m *overloadMatch) string {
+	codes := []string{}
+	for _, p := range m.problems {
+		codes = append(codes, p.Param+":"+p.Code)
+	}
+	return strings.Join(codes, ",")
+}
+
+func TestMatchOverloadProblems(t *testing.T) {
+	ov := &testSignature().Overloads[0]
+	tests := []struct {
+		name   string
+		req    ProcedureRequest
+		want   string
+		byName bool
+	}{
+		{"ok posicional", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "a", Value: 1.0}, {Name: "b", Value: "x"}, {Name: "r", Direction: "OUT"},
+		}}, "", false},
+		{"ok por nombre", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "p_result", Direction: "OUT"}, {Name: "P_ID", Value: "12"},
+		}}, "", true},
+		{"falta obligatorio", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "P_RESULT", Direction: "OUT"},
+		}}, "P_ID:missing", true},
+		{"desconocido y repetido", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "P_ID", Value: 1.0}, {Name: "P_ID", Value: 2.0}, {Name: "P_OTRO", Value: 1.0}, {Name: "P_RESULT", Direction: "OUT"},
+		}}, "P_ID:unknown,P_OTRO:unknown", true},
+		{"sobra", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "a", Value: 1.0}, {Name: "b", Value: "x"}, {Name: "r", Direction: "OUT"}, {Name: "d", Value: 1.0},
+		}}, "d:unknown", false},
+		{"dirección", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "P_ID", Direction: "OUT"}, {Name: "P_RESULT", Value: "x"},
+		}}, "P_ID:direction,P_RESULT:direction", true},
+		{"tipo", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "P_ID", Value: "abc"}, {Name: "P_RESULT", Direction: "OUT"},
+		}}, "P_ID:type", true},
+		{"demasiado largo", ProcedureRequest{Params: []ProcedureParam{
+			{Name: "P_ID", Value: 1.0}, {Name: "P_NOMBRE", Value: "ñandú-ñandú"}, {Name: "P_RESULT", Direction: "OUT"},
+		}}, "P_NOMBRE:too_long", true},
+		{"es procedimiento", ProcedureRequest{IsFunction: true, Params: []ProcedureParam{
+			{Name: "P_RESULT", Direction: "OUT"},
+		}}, ":kind", false},
+	}
+	for _, tt := range tests {
+		m := matchOverload(&tt.req, ov)
+		if got := problemCodes(m); got != tt.want {
+			t.Errorf("%s: problemas = %q, se esperaba %q", tt.name, got, tt.want)
+		}
+		if m.byName != tt.byName {
+			t.Errorf("%s: byName = %v, se esperaba %v", tt.name, m.byName, tt.byName)
+		}
+	}
+}
+
+func TestMatchOverloadDateParam(t *testing.T) {
+	ov := &testSignature().Overloads[1]
+	req := &ProcedureRequest{Params: []ProcedureParam{{Name: "P_FECHA", Value: "no-es-fecha"}, {Name: "P_RESULT", Direction: "OUT"}}}
+	if got := problemCodes(matchOverload(req, ov)); got != "P_FECHA:date" {
+		t.Errorf("problemas = %q, se esperaba P_FECHA:date", got)
+	}
+}
+
+func TestMatchSignatureChoosesOverload(t *testing.T) {
+	req := &ProcedureRequest{Name: "PKG.REGISTRAR", Params: []ProcedureParam{
+		{Name: "P_FECHA", Value: "2024-03-01"}, {Name: "P_RESULT", Direction: "OUT"},
+	}}
+	if err := matchSignature(req, testSignature()); err != nil {
+		t.Fatal(err)
+	}
+	// Con varias sobrecargas se invoca con notación con nombre y se toma el tipo de la firma
+	if len(req.argNames) != 2 || req.argNames[0] != "P_FECHA" || req.argNames[1] != "P_RESULT" {
+		t.Errorf("argNames = %v", req.argNames)
+	}
+	if req.Params[0].Type != "date" || req.Params[0].oracleType != "DATE" {
+		t.Errorf("tipo no aplicado: %+v", req.Params[0])
+	}
+}
+
+func TestMatchSignatureErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		req  ProcedureRequest
+		want string
+	}{
+		// Un booleano no encaja en NUMBER ni en DATE
+		{"ninguna", ProcedureRequest{Params: []ProcedureParam{{Name: "x", Value: true}, {Name: "r", Direction: "OUT"}}},
+			"Ninguna sobrecarga"},
+		{"sobrecarga inexistente", ProcedureRequest{Overload: 3, Params: []ProcedureParam{{Name: "r", Direction: "OUT"}}},
+			"La sobrecarga 3 no existe"},
+		{"sobrecarga indicada", ProcedureRequest{Overload: 2, Params: []ProcedureParam{{Name: "P_ID", Value: 1.0}, {Name: "P_RESULT", Direction: "OUT"}}},
+			"Parámetros inválidos"},
+	}
+	for _, tt := range tests {
+		req := tt.req
+		err := matchSignature(&req, testSignature())
+		var perr *procedureError
+		if !errors.As(err, &perr) || perr.Status != http.StatusBadRequest || !strings.HasPrefix(perr.Msg, tt.want) {
+			t.Errorf("%s: error = %v, se esperaba 400 %q", tt.name, err, tt.want)
+			continue
+		}
+		// Un error no modifica el request
+		if req.argNames != nil || req.Params[0].oracleType != "" {
+			t.Errorf("%s: el request se modificó: %+v", tt.name, req)
+		}
+	}
+}
+
+func TestMatchSignatureAmbiguous(t *testing.T) {
+	// PKG.BUSCAR(P_ID NUMBER) y PKG.BUSCAR(P_ID VARCHAR2): un null encaja en ambas sin coincidencias exactas
+	sig := &ProgramSignature{FullName: "APP.PKG.BUSCAR", Overloads: []ProgramOverload{
+		{Overload: 1, Type: "PROCEDURE", Params: []ProgramArgument{{Name: "P_ID", Position: 1, Direction: "IN", DataType: "NUMBER", Type: "number"}}},
+		{Overload: 2, Type: "PROCEDURE", Params: []ProgramArgument{{Name: "P_ID", Position: 1, Direction: "IN", DataType: "VARCHAR2", Type: "string"}}},
+	}}
+	req := &ProcedureRequest{Params: []ProcedureParam{{Name: "P_ID"}}}
+	var perr *procedureError
+	if err := matchSignature(req, sig); !errors.As(err, &perr) || !strings.HasPrefix(perr.Msg, "Llamada ambigua") {
+		t.Errorf("error = %v, se esperaba llamada ambigua", err)
+	}
+
+	// El tipo indicado desempata
+	req = &ProcedureRequest{Params: []ProcedureParam{{Name: "P_ID", Type: "string"}}}
+	if err := matchSignature(req, sig); err != nil {
+		t.Fatal(err)
+	}
+	if req.Params[0].oracleType != "VARCHAR2" {
+		t.Errorf("se esperaba la sobrecarga 2, tipo = %q", req.Params[0].oracleType)
+	}
+}
+
+// Por posición "x" es DATE: solo encaja en la sobrecarga 2
+func TestMatchSignaturePositionalType(t *testing.T) {
+	req := &ProcedureRequest{Params: []ProcedureParam{{Name: "x", Type: "date"}, {Name: "r", Direction: "OUT", Type: "string"}}}
+	if err := matchSignature(req, testSignature()); err != nil {
+		t.Fatal(err)
+	}
+	if req.argNames[0] != "P_FECHA" {
+		t.Errorf("se esperaba la sobrecarga 2, argNames = %v", req.argNames)
+	}
+}
+
+func TestSignatureCache(t *testing.T) {
+	signatureCache.mu.Lock()
+	saved := signatureCache.entries
+	signatureCache.entries = map[string]signatureEntry{signatureKey("app", "pkg.registrar"): {sig: testSignature(), loadedAt: time.Now()}}
+	signatureCache.mu.Unlock()
+	defer func() {
+		signatureCache.mu.Lock()
+		signatureCache.entries = saved
+		signatureCache.mu.Unlock()
+	}()
+
+	sig, cached, err := cachedSignature(context.Background(), "APP", "PKG.REGISTRAR")
+	if err != nil || !cached || sig == nil || sig.FullName != "APP.PKG.REGISTRAR" {
+		t.Fatalf("cachedSignature = %v, %v, %v; se esperaba la firma cacheada", sig, cached, err)
+	}
+	invalidateSignature("App", "Pkg.Registrar")
+	if len(signatureCache.entries) != 0 {
+		t.Errorf("invalidateSignature no quitó la firma: %v", signatureCache.entries)
+	}
+}
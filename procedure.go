@@ -0,0 +1,539 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// ProcedureParam describe un parámetro de /procedure y /procedure/async
+type ProcedureParam struct {
+	Name      string      `json:"name"`
+	Value     interface{} `json:"value,omitempty"`
+	Direction string      `json:"direction,omitempty"`
+	Type      string      `json:"type,omitempty"`      // "number", "string", "date", "timestamp", "timestamptz", "interval", "boolean"
+	TypeName  string      `json:"type_name,omitempty"` // Tipo Oracle para colecciones/objetos (p.ej. "T_NUM_LIST")
+
+	oracleType string // Tipo declarado en la firma (ALL_ARGUMENTS), si se pudo leer
+}
+
+// IsOut indica si el parámetro es de salida
+func (p ProcedureParam) IsOut() bool {
+	return strings.ToUpper(p.Direction) == "OUT"
+}
+
+// ProcedureRequest es el cuerpo común de /procedure y /procedure/async
+type ProcedureRequest struct {
+	Name          string           `json:"name"`
+	Schema        string           `json:"schema,omitempty"` // Esquema del procedimiento/función
+	Params        []ProcedureParam `json:"params"`
+	IsFunction    bool             `json:"isFunction,omitempty"`
+	CaptureOutput bool             `json:"capture_output,omitempty"` // Retornar las líneas de DBMS_OUTPUT
+	Overload      int              `json:"overload,omitempty"`       // Sobrecarga a invocar (ver GET /procedures/{nombre})
+	ResultSets    bool             `json:"result_sets,omitempty"`    // Leer los result sets implícitos (DBMS_SQL.RETURN_RESULT)
+
+	argNames        []string         // Con sobrecargas: argumento Oracle de cada parámetro (notación con nombre)
+	prepared        bool             // Ya pasó por prepareProcedureRequest
+	signatureCached bool             // Se validó contra una firma cacheada (puede estar desactualizada)
+	original        []ProcedureParam // Parámetros tal como llegaron, para volver a resolver la firma
+}
+
+// ProcedureResult es el resultado de una ejecución exitosa
+type ProcedureResult struct {
+	Out        map[string]interface{}
+	Output     []string                   // Líneas de DBMS_OUTPUT (solo con capture_output)
+	ResultSets [][]map[string]interface{} // Result sets implícitos (DBMS_SQL.RETURN_RESULT)
+	Truncated  bool                       // Algún result set superó resultSetMaxRows
+	Warnings   []string                   // Avisos para el cliente, p.ej. result sets que no se pudieron leer
+}
+
+// procedureError es un error de /procedure con el código HTTP a responder
+type procedureError struct {
+	Status  int
+	Msg     string
+	Output  []string               // DBMS_OUTPUT emitido antes del error, si se capturó
+	Details []paramProblem         // Problemas por parámetro detectados contra la firma del programa
+	Out     map[string]interface{} // Valores OUT cuando una regla de estado rechazó la llamada
+	Err     error                  // Error original del driver, si lo hubo
+}
+
+func (e *procedureError) Error() string {
+	return e.Msg
+}
+
+func (e *procedureError) Unwrap() error {
+	return e.Err
+}
+
+// procedureErrorBody arma la respuesta JSON de error con el detalle disponible
+func procedureErrorBody(err error) map[string]interface{} {
+	resp := map[string]interface{}{"error": err.Error()}
+	if perr, ok := err.(*procedureError); ok {
+		if perr.Details != nil {
+			resp["errors"] = perr.Details
+		}
+		if perr.Out != nil {
+			resp["out"] = perr.Out
+		}
+		if perr.Output != nil {
+			resp["dbms_output"] = perr.Output
+		}
+	}
+	return resp
+}
+
+// procedureErrorStatus retorna el código HTTP del error (500 si no es un procedureError)
+func procedureErrorStatus(err error) int {
+	if perr, ok := err.(*procedureError); ok {
+		return perr.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// procedureOut es un parámetro OUT ya enlazado; read convierte el valor leído a JSON
+type procedureOut struct {
+	name string
+	read func() interface{}
+}
+
+// procedureCall es la llamada PL/SQL lista para ejecutar
+type procedureCall struct {
+	SQL  string
+	args []interface{}
+	outs []procedureOut
+}
+
+// validateProcedureRequest hace las validaciones que no requieren consultar la base de datos
+func validateProcedureRequest(req *ProcedureRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("Falta el campo 'name'")
+	}
+	// Los arrays y objetos JSON solo se aceptan indicando el tipo Oracle (colección u objeto)
+	for _, p := range req.Params {
+		if p.TypeName == "" && isCompositeValue(p.Value) {
+			return fmt.Errorf("El parámetro '%s' es un array u objeto: indica 'type_name' con el tipo Oracle", p.Name)
+		}
+	}
+	if req.IsFunction && returnParamIndex(req.Params) == -1 {
+		return fmt.Errorf("Debe incluir un parámetro OUT para el valor de retorno")
+	}
+	return nil
+}
+
+// prepareProcedureRequest valida el request y resuelve la sobrecarga a invocar.
+// Los handlers lo llaman antes de responder para devolver 400 sin ejecutar nada.
+func prepareProcedureRequest(ctx context.Context, req *ProcedureRequest) error {
+	if req.prepared {
+		return nil
+	}
+	if err := validateProcedureRequest(req); err != nil {
+		return &procedureError{Status: http.StatusBadRequest, Msg: err.Error()}
+	}
+	if req.original == nil {
+		req.original = append([]ProcedureParam(nil), req.Params...)
+	}
+	if err := resolveOverload(ctx, req); err != nil {
+		return err
+	}
+	req.prepared = true
+	return nil
+}
+
+// returnParamIndex retorna la posición del primer parámetro OUT (valor de retorno de funciones)
+func returnParamIndex(params []ProcedureParam) int {
+	for i, p := range params {
+		if p.IsOut() {
+			return i
+		}
+	}
+	return -1
+}
+
+// numericOutKeywords son los fragmentos de nombre que hacen tratar un OUT sin tipo como numérico
+var numericOutKeywords = []string{"resultado", "result", "total", "count", "suma", "num", "int", "id"}
+
+// numericReturnKeywords es la lista (más acotada) usada para el valor de retorno de funciones
+var numericReturnKeywords = []string{"resultado", "total", "count", "suma", "num"}
+
+// isNumericOut decide si un OUT se lee como número: por tipo explícito o, sin tipo, por nombre
+func isNumericOut(p ProcedureParam, isReturn bool) bool {
+	switch strings.ToLower(p.Type) {
+	case "number", "integer", "float":
+		return true
+	case "":
+		keywords := numericOutKeywords
+		if isReturn {
+			keywords = numericReturnKeywords
+		}
+		lowerName := strings.ToLower(p.Name)
+		for _, kw := range keywords {
+			if strings.Contains(lowerName, kw) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bindOut prepara el destino de un parámetro OUT (o del retorno de una función).
+// Si el tipo PL/SQL no se puede enlazar directamente retorna la conversión a aplicar en el bloque.
+func bindOut(p ProcedureParam, isReturn bool) (interface{}, procedureOut, *bindConversion, error) {
+	out := procedureOut{name: p.Name}
+	if p.TypeName != "" {
+		bind, err := udtOutBindFor(p.TypeName)
+		if err != nil {
+			return nil, out, nil, fmt.Errorf("Parámetro '%s': %v", p.Name, err)
+		}
+		out.read = bind.Value
+		return bind.arg, out, nil, nil
+	}
+
+	switch strings.ToLower(p.Type) {
+	case "boolean":
+		boolPtr := new(sql.NullFloat64)
+		out.read = func() interface{} { return boolOutValue(boolPtr) }
+		return sql.Out{Dest: boolPtr}, out, boolConversion, nil
+	case "date", "timestamp", "timestamptz":
+		format := formatDateOutput
+		switch strings.ToLower(p.Type) {
+		case "timestamp":
+			format = formatTimestampOutput
+		case "timestamptz":
+			format = formatTimestampTZOutput
+		}
+		datePtr := new(sql.NullTime)
+		out.read = func() interface{} {
+			if datePtr.Valid {
+				return format(datePtr.Time)
+			}
+			return nil
+		}
+		return sql.Out{Dest: datePtr}, out, nil, nil
+	case "interval":
+		conv := intervalDSConversion
+		if isYearMonthInterval(p.oracleType) {
+			conv = intervalYMConversion
+		}
+		strPtr := new(string)
+		*strPtr = strings.Repeat(" ", 100)
+		out.read = func() interface{} { return formatIntervalOutput(*strPtr) }
+		return sql.Out{Dest: strPtr}, out, conv, nil
+	}
+	if isNumericOut(p, isReturn) {
+		numPtr := new(sql.NullFloat64)
+		out.read = func() interface{} {
+			if numPtr.Valid {
+				return numPtr.Float64
+			}
+			return nil
+		}
+		return sql.Out{Dest: numPtr}, out, nil, nil
+	}
+	// Buffer de 4000 caracteres para strings
+	strPtr := new(string)
+	*strPtr = strings.Repeat(" ", 4000)
+	out.read = func() interface{} { return *strPtr }
+	return sql.Out{Dest: strPtr}, out, nil, nil
+}
+
+// bindIn convierte el valor de un parámetro IN al argumento a enlazar
+func bindIn(p ProcedureParam) (interface{}, *bindConversion, error) {
+	if p.TypeName != "" {
+		arg, _, err := udtBindArg(p.TypeName, "IN", p.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Parámetro '%s': %v", p.Name, err)
+		}
+		return arg, nil, nil
+	}
+
+	pTypeLower := strings.ToLower(p.Type)
+	switch pTypeLower {
+	case "boolean":
+		v, err := parseBoolParam(p.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Parámetro '%s': %v", p.Name, err)
+		}
+		return v, boolConversion, nil
+	case "timestamp", "timestamptz":
+		if p.Value == nil {
+			return nil, nil, nil
+		}
+		// time.Time se enlaza como TIMESTAMP WITH TIME ZONE, conservando fracciones y offset
+		t, err := parseTimestampParam(p.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Parámetro '%s': %v", p.Name, err)
+		}
+		return t, nil, nil
+	case "interval":
+		if p.Value == nil {
+			conv := intervalDSConversion
+			if isYearMonthInterval(p.oracleType) {
+				conv = intervalYMConversion
+			}
+			return nil, conv, nil
+		}
+		v, yearMonth, err := parseIntervalParam(p.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Parámetro '%s': %v", p.Name, err)
+		}
+		if yearMonth {
+			return v, intervalYMConversion, nil
+		}
+		return v, intervalDSConversion, nil
+	}
+
+	// Verificar si es fecha por tipo explícito o por nombre
+	pNameLower := strings.ToLower(p.Name)
+	isDateType := pTypeLower == "date"
+	isDateName := strings.Contains(pNameLower, "fecha") || strings.Contains(pNameLower, "periodo")
+	if isDateType || isDateName {
+		parsedTime, err := parseDateParam(p.Value)
+		if err == nil {
+			return parsedTime, nil, nil
+		}
+		if isDateType {
+			// Fecha con hora en ISO-8601
+			if parsedTime, tsErr := parseTimestampParam(p.Value); tsErr == nil {
+				return parsedTime, nil, nil
+			}
+		}
+		// Si falla, loguear y usar valor original
+		log.Printf("[PROCEDURE] Advertencia al parsear fecha en parámetro '%s': %v", p.Name, err)
+	}
+	return p.Value, nil, nil
+}
+
+// buildProcedureCall construye el SQL y los binds de la llamada.
+// En funciones el primer parámetro OUT es el valor de retorno y se enlaza en :1.
+// Si se resolvió una sobrecarga se usa notación con nombre (ARG => :n).
+func buildProcedureCall(req *ProcedureRequest) (*procedureCall, error) {
+	call := &procedureCall{}
+	conversions := make(map[int]*bindConversion)
+	placeholders := []string{}
+	argNames := []string{} // Alineado con call.args; vacío si la llamada es posicional
+
+	retIndex := -1
+	if req.IsFunction {
+		retIndex = returnParamIndex(req.Params)
+		if retIndex == -1 {
+			return nil, fmt.Errorf("Debe incluir un parámetro OUT para el valor de retorno")
+		}
+		arg, out, conv, err := bindOut(req.Params[retIndex], true)
+		if err != nil {
+			return nil, err
+		}
+		if conv != nil {
+			conversions[0] = conv
+		}
+		call.args = append(call.args, arg)
+		call.outs = append(call.outs, out)
+		argNames = append(argNames, "")
+	}
+
+	for i, p := range req.Params {
+		if i == retIndex {
+			continue
+		}
+		placeholder := fmt.Sprintf(":%d", len(call.args)+1)
+		argName := ""
+		if req.argNames != nil {
+			argName = req.argNames[i]
+			placeholder = fmt.Sprintf("%s => %s", argName, placeholder)
+		}
+		placeholders = append(placeholders, placeholder)
+		argNames = append(argNames, argName)
+		var arg interface{}
+		var conv *bindConversion
+		var err error
+		if p.IsOut() {
+			var out procedureOut
+			arg, out, conv, err = bindOut(p, false)
+			call.outs = append(call.outs, out)
+		} else {
+			arg, conv, err = bindIn(p)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if conv != nil {
+			conversions[len(call.args)] = conv
+		}
+		call.args = append(call.args, arg)
+	}
+
+	// Formatear el nombre para manejar esquema.procedimiento correctamente
+	objectName := formatObjectName(req.Schema, req.Name)
+	if req.IsFunction {
+		call.SQL = fmt.Sprintf("BEGIN :1 := %s(%s); END;", objectName, strings.Join(placeholders, ", "))
+	} else {
+		call.SQL = fmt.Sprintf("BEGIN %s(%s); END;", objectName, strings.Join(placeholders, ", "))
+	}
+	if len(conversions) > 0 {
+		if req.argNames == nil {
+			argNames = nil
+		}
+		call.SQL, call.args = buildConvertedCall(objectName, req.IsFunction, call.args, conversions, argNames)
+	}
+	return call, nil
+}
+
+// readOuts recopila los valores OUT ya convertidos a JSON
+func (c *procedureCall) readOuts() map[string]interface{} {
+	out := make(map[string]interface{}, len(c.outs))
+	for _, o := range c.outs {
+		out[o.name] = o.read()
+	}
+	return out
+}
+
+// describeCallError mejora los mensajes de error de Oracle más comunes
+func describeCallError(req *ProcedureRequest, err error) string {
+	errorMsg := err.Error()
+	kind, article := "Procedimiento", "El procedimiento"
+	notFound := "no encontrado"
+	if req.IsFunction {
+		kind, article = "Función", "La función"
+		notFound = "no encontrada"
+	}
+	if strings.Contains(errorMsg, "PLS-00201") {
+		return fmt.Sprintf("%s '%s' %s. Verifica que existe en la base de datos.", kind, req.Name, notFound)
+	} else if strings.Contains(errorMsg, "PLS-00306") {
+		return fmt.Sprintf("Parámetros incorrectos para '%s'. Verifica tipos y cantidad de parámetros.", req.Name)
+	} else if strings.Contains(errorMsg, "ORA-06502") {
+		return "Error de conversión de tipos. Verifica que los tipos de datos sean correctos."
+	} else if strings.Contains(errorMsg, "ORA-01403") {
+		return fmt.Sprintf("No se encontraron datos. %s no retornó resultados.", article)
+	}
+	return errorMsg
+}
+
+// executeProcedure ejecuta un procedimiento o función; es el motor común de /procedure y /procedure/async.
+// progress (opcional, jobs asíncronos) sigue el avance que informa el procedimiento (ver progress.go).
+func executeProcedure(ctx context.Context, req *ProcedureRequest, progress *progressTracker) (*ProcedureResult, error) {
+	if err := prepareProcedureRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	result, err := callProcedure(ctx, req, progress)
+	if err == nil || !req.signatureCached || !hasOraCode(err, "ORA-06550") {
+		return result, err
+	}
+
+	// ORA-06550 (p.ej. PLS-00306) es un error de compilación del bloque: no se ejecutó nada.
+	// Con una firma cacheada puede ser que el programa cambió; se relee y se reintenta una vez.
+	log.Printf("[PROCEDURE] %s falló con una firma cacheada, se vuelve a leer: %v", req.Name, err)
+	invalidateSignature(req.Schema, req.Name)
+	req.Params = append([]ProcedureParam(nil), req.original...)
+	req.argNames, req.prepared, req.signatureCached = nil, false, false
+	if perr := prepareProcedureRequest(ctx, req); perr != nil {
+		return nil, perr
+	}
+	return callProcedure(ctx, req, progress)
+}
+
+// hasOraCode indica si el error incluye el código ORA- indicado
+func hasOraCode(err error, code string) bool {
+	for _, c := range oraCodes(err) {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// callProcedure arma y ejecuta la llamada de un request ya preparado
+func callProcedure(ctx context.Context, req *ProcedureRequest, progress *progressTracker) (*ProcedureResult, error) {
+
+	call, err := buildProcedureCall(req)
+	if err != nil {
+		return nil, &procedureError{Status: http.StatusBadRequest, Msg: err.Error()}
+	}
+	log.Printf("[PROCEDURE] SQL generado: %s", call.SQL)
+
+	// Con result_sets la llamada se ejecuta como consulta para recibir los result sets implícitos.
+	// Por esa vía el driver no retorna binds OUT, así que con parámetros de salida la llamada se
+	// ejecuta como siempre y se avisa en la respuesta que los cursores no se leyeron.
+	readResults := req.ResultSets
+	var warnings []string
+	if readResults && len(call.outs) > 0 {
+		readResults = false
+		warnings = append(warnings, "'result_sets' se ignoró: los result sets implícitos no se pueden leer en llamadas con parámetros OUT/INOUT o funciones")
+	}
+
+	// Con capture_output la llamada se ejecuta en una conexión dedicada con DBMS_OUTPUT habilitado
+	var outConn *sql.Conn
+	if req.CaptureOutput {
+		conn, err := beginOutputCapture(ctx)
+		if err != nil {
+			return nil, &procedureError{Status: http.StatusInternalServerError, Msg: err.Error()}
+		}
+		outConn = conn
+		defer outConn.Close()
+	}
+
+	// Para seguir el avance la sesión se marca con el ID del job, así que también necesita conexión propia
+	callConn := outConn
+	if progress != nil {
+		if callConn == nil {
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				return nil, &procedureError{Status: http.StatusInternalServerError, Msg: err.Error()}
+			}
+			callConn = conn
+			defer callConn.Close()
+		}
+		defer progress.start(ctx, callConn)()
+	}
+
+	// failure arma el error incluyendo la salida previa, que suele ser el mejor diagnóstico
+	failure := func(err error) error {
+		perr := &procedureError{Status: http.StatusInternalServerError, Msg: describeCallError(req, err), Err: err}
+		if outConn != nil {
+			if lines, err := readDBMSOutput(ctx, outConn); err == nil {
+				perr.Output = lines
+			}
+		}
+		return perr
+	}
+
+	stmt, err := prepareCall(ctx, callConn, call.SQL)
+	if err != nil {
+		return nil, failure(err)
+	}
+	defer stmt.Close()
+
+	// Los programas que retornan cursores se ejecutan como consulta para recibir los result sets
+	var resultSets [][]map[string]interface{}
+	var truncated bool
+	if readResults {
+		rows, err := stmt.QueryContext(ctx, call.args...)
+		if err != nil {
+			return nil, failure(err)
+		}
+		resultSets, truncated, err = readResultSets(rows, resultSetMaxRows)
+		rows.Close()
+		if err != nil {
+			return nil, failure(err)
+		}
+	} else if _, err := stmt.ExecContext(ctx, call.args...); err != nil {
+		return nil, failure(err)
+	}
+
+	result := &ProcedureResult{Out: call.readOuts(), ResultSets: resultSets, Truncated: truncated, Warnings: warnings}
+	if outConn != nil {
+		lines, err := readDBMSOutput(ctx, outConn)
+		if err != nil {
+			log.Printf("[PROCEDURE] Advertencia al leer DBMS_OUTPUT: %v", err)
+		}
+		result.Output = lines
+	}
+
+	// Errores de negocio informados en parámetros OUT (API_STATUS_RULES)
+	if err := checkStatusRules(req, result); err != nil {
+		log.Printf("[PROCEDURE] %s rechazado por regla de estado: %v", req.Name, err)
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Seguimiento del avance real de los jobs asíncronos
+const (
+	jobProgressInterval = 2 * time.Second // Cada cuánto se consulta el avance durante la llamada
+	jobModuleName       = "go-oracle-api" // MODULE de las sesiones que ejecutan jobs
+	jobClientInfoPrefix = "job:"          // CLIENT_INFO = job:<id>, lo lee API_JOB_PROGRESS
+)
+
+// Si el usuario no puede leer V$SESSION_LONGOPS o falta ASYNC_JOB_PROGRESS se deja de consultar
+var (
+	longopsUnavailable       atomic.Bool
+	progressTableUnavailable atomic.Bool
+)
+
+// progressTracker sigue el avance real de la llamada de un job: marca la sesión con el ID del
+// job (MODULE/ACTION/CLIENT_INFO) y mientras se ejecuta jobProgress consulta lo que el
+// procedimiento informa en ASYNC_JOB_PROGRESS (paquete API_JOB_PROGRESS) o en V$SESSION_LONGOPS
+type progressTracker struct {
+	jobID  string
+	report func(percent int, message string)
+
+	sid         string    // Sesión de la llamada
+	since       time.Time // Inicio de la llamada según Oracle (filtra V$SESSION_LONGOPS)
+	lastPercent int
+	lastMessage string
+}
+
+// start marca la sesión de la llamada y la registra en jobProgress; la función retornada deja
+// de seguirla y limpia la marca antes de que la conexión vuelva al pool
+func (t *progressTracker) start(ctx context.Context, conn *sql.Conn) func() {
+	_, err := conn.ExecContext(ctx, `BEGIN
+		DBMS_APPLICATION_INFO.SET_MODULE(:1, :2);
+		DBMS_APPLICATION_INFO.SET_CLIENT_INFO(:3);
+	END;`, jobModuleName, t.jobID, jobClientInfoPrefix+t.jobID)
+	if err != nil {
+		log.Printf("[JOBS] Job %s: no se pudo marcar la sesión: %v", t.jobID, err)
+	}
+	if err := conn.QueryRowContext(ctx, "SELECT SYS_CONTEXT('USERENV', 'SID'), SYSDATE FROM DUAL").Scan(&t.sid, &t.since); err != nil {
+		log.Printf("[JOBS] Job %s: no se pudo obtener la sesión: %v", t.jobID, err)
+	}
+	t.lastPercent = -1
+	jobProgress.add(t)
+
+	return func() {
+		jobProgress.remove(t)
+		cleanup, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn.ExecContext(cleanup, `BEGIN
+			DBMS_APPLICATION_INFO.SET_MODULE(NULL, NULL);
+			DBMS_APPLICATION_INFO.SET_CLIENT_INFO(NULL);
+		END;`)
+		if !progressTableUnavailable.Load() {
+			db.ExecContext(cleanup, "DELETE FROM ASYNC_JOB_PROGRESS WHERE JOB_ID = :1", t.jobID)
+		}
+	}
+}
+
+// progressPoller consulta el avance de todos los jobs en ejecución de esta instancia con una
+// consulta por fuente cada jobProgressInterval, en conexiones del pool que se liberan enseguida.
+// La goroutine corre solo mientras hay jobs registrados.
+type progressPoller struct {
+	mu      sync.Mutex // También se toma al informar: después de remove no llegan más avances
+	jobs    map[string]*progressTracker
+	running bool
+}
+
+var jobProgress = &progressPoller{jobs: make(map[string]*progressTracker)}
+
+// add registra un job y arranca la goroutine si no estaba corriendo
+func (p *progressPoller) add(t *progressTracker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.jobs[t.jobID] = t
+	if !p.running {
+		p.running = true
+		go p.run()
+	}
+}
+
+// remove deja de seguir un job; al retornar ya no se informan avances de ese job
+func (p *progressPoller) remove(t *progressTracker) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.jobs[t.jobID] == t {
+		delete(p.jobs, t.jobID)
+	}
+}
+
+func (p *progressPoller) run() {
+	ticker := time.NewTicker(jobProgressInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.mu.Lock()
+		trackers := make([]*progressTracker, 0, len(p.jobs))
+		for _, t := range p.jobs {
+			trackers = append(trackers, t)
+		}
+		if len(trackers) == 0 {
+			p.running = false
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), jobProgressInterval)
+		updates := p.read(ctx, trackers)
+		cancel()
+
+		p.mu.Lock()
+		for _, t := range trackers {
+			u, ok := updates[t.jobID]
+			if !ok || p.jobs[t.jobID] != t || (u.percent == t.lastPercent && u.message == t.lastMessage) {
+				continue
+			}
+			t.lastPercent, t.lastMessage = u.percent, u.message
+			t.report(u.percent, u.message)
+		}
+		p.mu.Unlock()
+	}
+}
+
+// progressUpdate es el avance leído de un job
+type progressUpdate struct {
+	percent int
+	message string
+}
+
+// read retorna el avance informado por cada job: primero ASYNC_JOB_PROGRESS y, para los que no
+// tienen registro, la operación más reciente de su sesión en V$SESSION_LONGOPS
+func (p *progressPoller) read(ctx context.Context, trackers []*progressTracker) map[string]progressUpdate {
+	updates := make(map[string]progressUpdate)
+	if !progressTableUnavailable.Load() {
+		if err := readReportedProgress(ctx, trackers, updates); err != nil {
+			if strings.Contains(err.Error(), "ORA-00942") {
+				progressTableUnavailable.Store(true)
+				log.Printf("[JOBS] Tabla ASYNC_JOB_PROGRESS no disponible; el avance se toma solo de V$SESSION_LONGOPS")
+			} else {
+				log.Printf("[JOBS] Error leyendo ASYNC_JOB_PROGRESS: %v", err)
+			}
+		}
+	}
+
+	if longopsUnavailable.Load() {
+		return updates
+	}
+	pending := []*progressTracker{}
+	for _, t := range trackers {
+		if _, ok := updates[t.jobID]; !ok && t.sid != "" {
+			pending = append(pending, t)
+		}
+	}
+	if len(pending) == 0 {
+		return updates
+	}
+	if err := readLongopsProgress(ctx, pending, updates); err != nil {
+		if strings.Contains(err.Error(), "ORA-00942") || strings.Contains(err.Error(), "ORA-01031") {
+			longopsUnavailable.Store(true)
+			log.Printf("[JOBS] Sin acceso a V$SESSION_LONGOPS (GRANT SELECT ON V_$SESSION_LONGOPS): %v", err)
+		} else {
+			log.Printf("[JOBS] Error leyendo V$SESSION_LONGOPS: %v", err)
+		}
+	}
+	return updates
+}
+
+// readReportedProgress lee de ASYNC_JOB_PROGRESS el avance de los jobs indicados
+func readReportedProgress(ctx context.Context, trackers []*progressTracker, updates map[string]progressUpdate) error {
+	placeholders := make([]string, len(trackers))
+	args := make([]interface{}, len(trackers))
+	for i, t := range trackers {
+		placeholders[i] = fmt.Sprintf(":%d", i+1)
+		args[i] = t.jobID
+	}
+	rows, err := db.QueryContext(ctx, "SELECT JOB_ID, PERCENT, MESSAGE FROM ASYNC_JOB_PROGRESS WHERE JOB_ID IN ("+
+		strings.Join(placeholders, ", ")+")", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var jobID string
+		var percent sql.NullFloat64
+		var message sql.NullString
+		if err := rows.Scan(&jobID, &percent, &message); err != nil {
+			return err
+		}
+		updates[jobID] = progressUpdate{percent: clampPercent(percent.Float64), message: message.String}
+	}
+	return rows.Err()
+}
+
+// readLongopsProgress lee de V$SESSION_LONGOPS la operación más reciente de la sesión de cada
+// job, iniciada después de que empezó su llamada
+func readLongopsProgress(ctx context.Context, trackers []*progressTracker, updates map[string]progressUpdate) error {
+	placeholders := make([]string, len(trackers))
+	args := make([]interface{}, 0, len(trackers)+1)
+	bySID := make(map[string]*progressTracker, len(trackers))
+	since := trackers[0].since
+	for i, t := range trackers {
+		placeholders[i] = fmt.Sprintf(":%d", i+1)
+		args = append(args, t.sid)
+		bySID[t.sid] = t
+		if t.since.Before(since) {
+			since = t.since
+		}
+	}
+	args = append(args, since)
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT TO_CHAR(SID), SOFAR, TOTALWORK, OPNAME, MESSAGE, START_TIME FROM V$SESSION_LONGOPS
+		WHERE SID IN (%s) AND START_TIME >= :%d AND TOTALWORK > 0
+		ORDER BY LAST_UPDATE_TIME DESC`, strings.Join(placeholders, ", "), len(args)), args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var sid string
+		var sofar, total float64
+		var opname, message sql.NullString
+		var started time.Time
+		if err := rows.Scan(&sid, &sofar, &total, &opname, &message, &started); err != nil {
+			return err
+		}
+		t, ok := bySID[sid]
+		if !ok || started.Before(t.since) {
+			continue
+		}
+		if _, done := updates[t.jobID]; done {
+			continue // Ya se tomó la operación más reciente
+		}
+		text := message.String
+		if text == "" {
+			text = opname.String
+		}
+		updates[t.jobID] = progressUpdate{percent: clampPercent(sofar * 100 / total), message: text}
+	}
+	return rows.Err()
+}
+
+// clampPercent redondea el avance a un entero entre 0 y 100
+func clampPercent(p float64) int {
+	if math.IsNaN(p) || p < 0 {
+		return 0
+	}
+	if p > 100 {
+		return 100
+	}
+	return int(math.Round(p))
+}
+
+// createJobProgressObjects crea la tabla ASYNC_JOB_PROGRESS y el paquete API_JOB_PROGRESS con
+// el que los procedimientos informan su avance (ver sql/create_job_progress.sql)
+func createJobProgressObjects() error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM USER_TABLES WHERE TABLE_NAME = 'ASYNC_JOB_PROGRESS'").Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		log.Println("[JOBS] Creando tabla ASYNC_JOB_PROGRESS...")
+		_, err := db.Exec(`
+			CREATE TABLE ASYNC_JOB_PROGRESS (
+				JOB_ID VARCHAR2(32) PRIMARY KEY,
+				PERCENT NUMBER(5,2),
+				MESSAGE VARCHAR2(4000),
+				UPDATED_AT TIMESTAMP DEFAULT SYSTIMESTAMP
+			)`)
+		if err != nil {
+			return fmt.Errorf("error creando tabla ASYNC_JOB_PROGRESS: %v", err)
+		}
+	}
+
+	// Un cuerpo de una versión anterior (sin acotar el avance) se reemplaza
+	if err := db.QueryRow(`SELECT COUNT(*) FROM USER_SOURCE WHERE NAME = 'API_JOB_PROGRESS' AND TYPE = 'PACKAGE BODY'
+		AND UPPER(TEXT) LIKE '%LEAST(100,%'`).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	log.Println("[JOBS] Creando paquete API_JOB_PROGRESS...")
+	if _, err := db.Exec(`
+		CREATE OR REPLACE PACKAGE API_JOB_PROGRESS AS
+			-- ID del job que ejecuta la sesión (NULL fuera de un job de la API)
+			FUNCTION job_id RETURN VARCHAR2;
+			-- Informa el avance (0-100) y un mensaje; no hace nada fuera de un job
+			PROCEDURE report(p_percent IN NUMBER, p_message IN VARCHAR2 DEFAULT NULL);
+		END API_JOB_PROGRESS;`); err != nil {
+		return fmt.Errorf("error creando paquete API_JOB_PROGRESS: %v", err)
+	}
+	if _, err := db.Exec(`
+		CREATE OR REPLACE PACKAGE BODY API_JOB_PROGRESS AS
+			FUNCTION job_id RETURN VARCHAR2 IS
+				v_info VARCHAR2(64) := SYS_CONTEXT('USERENV', 'CLIENT_INFO');
+			BEGIN
+				IF v_info LIKE '` + jobClientInfoPrefix + `%' THEN
+					RETURN SUBSTR(v_info, ` + fmt.Sprint(len(jobClientInfoPrefix)+1) + `);
+				END IF;
+				RETURN NULL;
+			END job_id;
+
+			PROCEDURE report(p_percent IN NUMBER, p_message IN VARCHAR2 DEFAULT NULL) IS
+				PRAGMA AUTONOMOUS_TRANSACTION;
+				v_job_id VARCHAR2(32) := job_id;
+				v_percent NUMBER;
+				v_message VARCHAR2(4000);
+			BEGIN
+				IF v_job_id IS NULL THEN
+					RETURN;
+				END IF;
+				v_percent := GREATEST(0, LEAST(100, p_percent));
+				v_message := SUBSTRB(p_message, 1, 4000);
+				MERGE INTO ASYNC_JOB_PROGRESS p
+				USING (SELECT v_job_id AS JOB_ID FROM DUAL) s ON (p.JOB_ID = s.JOB_ID)
+				WHEN MATCHED THEN UPDATE SET PERCENT = v_percent, MESSAGE = v_message, UPDATED_AT = SYSTIMESTAMP
+				WHEN NOT MATCHED THEN INSERT (JOB_ID, PERCENT, MESSAGE) VALUES (v_job_id, v_percent, v_message);
+				COMMIT;
+			EXCEPTION
+				WHEN OTHERS THEN
+					ROLLBACK; -- Informar el avance nunca hace fallar al procedimiento
+			END report;
+		END API_JOB_PROGRESS;`); err != nil {
+		return fmt.Errorf("error creando cuerpo de API_JOB_PROGRESS: %v", err)
+	}
+	log.Println("[JOBS] Paquete API_JOB_PROGRESS creado")
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClampPercent(t *testing.T) {
+	tests := map[float64]int{
+		-5:          0,
+		0:           0,
+		0.4:         0,
+		0.5:         1,
+		49.5:        50,
+		99.6:        100,
+		100:         100,
+		250:         100,
+		math.NaN():  0,
+		math.Inf(1): 100,
+	}
+	for in, want := range tests {
+		if got := clampPercent(in); got != want {
+			t.Errorf("clampPercent(%v) = %d, se esperaba %d", in, got, want)
+		}
+	}
+}
+
+// Un tracker reemplazado (p.ej. el reintento de un job) no borra al que lo reemplazó
+func TestProgressPollerRemove(t *testing.T) {
+	p := &progressPoller{jobs: make(map[string]*progressTracker), running: true}
+	first := &progressTracker{jobID: "job-1"}
+	second := &progressTracker{jobID: "job-1"}
+	p.add(first)
+	p.add(second)
+
+	p.remove(first)
+	if p.jobs["job-1"] != second {
+		t.Fatal("remove del tracker anterior quitó el actual")
+	}
+	p.remove(second)
+	if len(p.jobs) != 0 {
+		t.Errorf("quedaron jobs registrados: %v", p.jobs)
+	}
+}
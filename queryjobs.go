@@ -0,0 +1,567 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Consultas asíncronas: POST /query/async ejecuta un SELECT como job y guarda el resultado en
+// ASYNC_QUERY_RESULTS en bloques de filas (JSON en un BLOB), que se leen con GET /jobs/{id}/rows
+const (
+	queryJobName          = "query" // procedure_name de los jobs de consultas
+	queryResultChunkRows  = 1000    // Filas por bloque de ASYNC_QUERY_RESULTS
+	queryRowsDefaultLimit = 100
+	queryRowsMaxLimit     = 5000
+)
+
+// asyncQueryRequest es el cuerpo de /query/async
+type asyncQueryRequest struct {
+	Query       string `json:"query"`
+	Priority    int    `json:"priority,omitempty"`     // 1 (baja) a 10 (alta); por defecto 5
+	CallbackURL string `json:"callback_url,omitempty"` // Recibe el job final al terminar (ver webhook.go)
+}
+
+// queryJobSQL retorna la consulta de un job creado con /query/async
+func queryJobSQL(job *AsyncJob) (string, bool) {
+	if job.ProcName != queryJobName {
+		return "", false
+	}
+	query, ok := job.Params["query"].(string)
+	return query, ok
+}
+
+// normalizeAsyncQuery normaliza los saltos de línea como /query y valida que sea un SELECT
+func normalizeAsyncQuery(query string) (string, error) {
+	query = strings.ReplaceAll(query, "\r\n", "\n")
+	query = strings.ReplaceAll(query, "\\n", "\n")
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+	if query == "" {
+		return "", fmt.Errorf("Falta el campo 'query'")
+	}
+	words := strings.Fields(strings.ToUpper(strings.TrimLeft(query, "( \t\n")))
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		return "", fmt.Errorf("Solo se admiten consultas SELECT (o WITH ... SELECT)")
+	}
+	return query, nil
+}
+
+// asyncQueryHandler encola un SELECT para ejecutarse en segundo plano (POST /query/async)
+func asyncQueryHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(&w, r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite POST"})
+		return
+	}
+
+	var body asyncQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
+		return
+	}
+	query, err := normalizeAsyncQuery(body.Query)
+	var priority int
+	if err == nil {
+		priority, err = jobPriority(body.Priority)
+	}
+	if err == nil && body.CallbackURL != "" {
+		err = webhooks.validateCallbackURL(body.CallbackURL)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	if jobQueue.Full() {
+		writeQueueFull(w)
+		return
+	}
+
+	// Un SELECT se puede repetir sin efectos: si la instancia se detiene, el job se reencola
+	job, err := submitJob(queryJobName, map[string]interface{}{"query": query}, jobOptions{
+		Priority:    priority,
+		CallbackURL: body.CallbackURL,
+		OnInterrupt: OnInterruptRequeue,
+		SubmittedBy: clientIP(r),
+	}, func(job *AsyncJob) *queuedJob {
+		return queryQueuedJob(job, query)
+	})
+	if err != nil {
+		writeQueueFull(w)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "accepted",
+		"job_id":           job.ID,
+		"priority":         job.Priority,
+		"message":          "Consulta encolada para ejecutarse en segundo plano",
+		"check_status_url": fmt.Sprintf("/jobs/%s", job.ID),
+		"rows_url":         fmt.Sprintf("/jobs/%s/rows", job.ID),
+	})
+}
+
+// queryQueuedJob arma la entrada de la cola que ejecuta la consulta de un job. Las consultas
+// comparten el límite por procedimiento de "query" (ASYNC_PROCEDURE_LIMITS).
+func queryQueuedJob(job *AsyncJob, query string) *queuedJob {
+	jobID := job.ID
+	return &queuedJob{
+		id:       jobID,
+		program:  programKey("", queryJobName),
+		priority: job.Priority,
+		queuedAt: job.StartTime,
+		run:      func(ctx context.Context) { runQueryJob(ctx, jobID, query) },
+	}
+}
+
+// runQueryJob ejecuta la consulta de un job y guarda las filas en ASYNC_QUERY_RESULTS a medida
+// que se leen, sin tener el resultado completo en memoria
+func runQueryJob(ctx context.Context, jobID, query string) {
+	defer func() {
+		if r := recover(); r != nil {
+			endTime := time.Now()
+			deleteQueryResults(jobID)
+			jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+				j.Status = JobStatusFailed
+				j.Error = fmt.Sprintf("Panic recuperado: %v", r)
+				j.EndTime = &endTime
+				j.Duration = endTime.Sub(j.StartTime).String()
+				j.Progress = 100
+			})
+			log.Printf("[JOBS] Panic en consulta %s: %v", jobID, r)
+		}
+	}()
+
+	start := time.Now()
+	var submittedBy string
+	jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+		j.Status = JobStatusRunning
+		j.StartTime = start
+		j.Progress = 0
+		j.ProgressMessage = ""
+		j.Attempt++
+		submittedBy = j.SubmittedBy
+	})
+	qlog := &QueryLog{
+		ID:            generateID(),
+		QueryType:     "QUERY_ASYNC",
+		QueryText:     query,
+		ExecutionTime: start,
+		UserIP:        submittedBy,
+	}
+
+	log.Printf("[QUERY] Job %s ejecutando: %s", jobID, query)
+	deleteQueryResults(jobID) // Filas de una ejecución anterior interrumpida
+	columns, types, count, err := spoolQuery(ctx, jobID, query)
+
+	endTime := time.Now()
+	qlog.Duration = endTime.Sub(start).String()
+	qlog.Success = err == nil
+	qlog.RowsAffected = count
+	if err != nil {
+		qlog.ErrorMsg = err.Error()
+		deleteQueryResults(jobID)
+	}
+	go saveQueryLog(qlog)
+
+	jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+		j.EndTime = &endTime
+		j.Duration = endTime.Sub(j.StartTime).String()
+		j.Progress = 100
+		switch {
+		case err != nil && ctx.Err() != nil:
+			j.Status = JobStatusCancelled
+			j.Error = "Job cancelado durante la ejecución"
+		case err != nil:
+			j.Status = JobStatusFailed
+			j.Error = err.Error()
+		default:
+			j.Status = JobStatusCompleted
+			j.Error = ""
+			j.ProgressMessage = fmt.Sprintf("%d filas", count)
+			j.Result = map[string]interface{}{
+				"columns":      columns,
+				"column_types": types,
+				"row_count":    count,
+				"rows_url":     fmt.Sprintf("/jobs/%s/rows", jobID),
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("[QUERY] Job %s terminó con error: %v", jobID, err)
+	} else {
+		log.Printf("[QUERY] Job %s completado: %d filas", jobID, count)
+	}
+}
+
+// spoolQuery ejecuta la consulta y guarda sus filas en bloques de queryResultChunkRows
+func spoolQuery(ctx context.Context, jobID, query string) (columns, types []string, count int64, err error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer rows.Close()
+
+	if columns, err = rows.Columns(); err != nil {
+		return nil, nil, 0, err
+	}
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	types = make([]string, len(colTypes))
+	for i, ct := range colTypes {
+		types[i] = ct.DatabaseTypeName()
+	}
+
+	chunk := make([][]interface{}, 0, queryResultChunkRows)
+	chunkNo := 0
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		_, err = db.ExecContext(ctx, `INSERT INTO ASYNC_QUERY_RESULTS (JOB_ID, CHUNK_NO, FIRST_ROW, ROW_COUNT, DATA)
+			VALUES (:1, :2, :3, :4, :5)`, jobID, chunkNo, count-int64(len(chunk)), len(chunk), data)
+		if err != nil {
+			return fmt.Errorf("error guardando el resultado en ASYNC_QUERY_RESULTS: %v", err)
+		}
+		chunkNo++
+		chunk = chunk[:0]
+		jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+			j.ProgressMessage = fmt.Sprintf("%d filas leídas", count)
+		})
+		return nil
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, count, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		chunk = append(chunk, values)
+		count++
+		if len(chunk) == queryResultChunkRows {
+			if err := flush(); err != nil {
+				return nil, nil, count, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, count, err
+	}
+	return columns, types, count, flush()
+}
+
+// deleteQueryResults borra las filas guardadas de un job
+func deleteQueryResults(jobID string) {
+	if _, err := db.Exec("DELETE FROM ASYNC_QUERY_RESULTS WHERE JOB_ID = :1", jobID); err != nil {
+		log.Printf("[QUERY] Error borrando el resultado del job %s: %v", jobID, err)
+	}
+}
+
+// readQueryRows recorre las filas guardadas desde offset; limit <= 0 lee hasta el final
+func readQueryRows(ctx context.Context, jobID string, offset, limit int64, fn func(row []json.RawMessage) error) error {
+	query := `SELECT FIRST_ROW, DATA FROM ASYNC_QUERY_RESULTS
+		WHERE JOB_ID = :1 AND FIRST_ROW + ROW_COUNT > :2`
+	args := []interface{}{jobID, offset}
+	if limit > 0 {
+		query += " AND FIRST_ROW < :3"
+		args = append(args, offset+limit)
+	}
+	rows, err := db.QueryContext(ctx, query+" ORDER BY CHUNK_NO", args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	sent := int64(0)
+	for rows.Next() {
+		var first int64
+		var data []byte
+		if err := rows.Scan(&first, &data); err != nil {
+			return err
+		}
+		var chunk [][]json.RawMessage
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return err
+		}
+		for i, row := range chunk {
+			if first+int64(i) < offset {
+				continue
+			}
+			if limit > 0 && sent >= limit {
+				return nil
+			}
+			if err := fn(row); err != nil {
+				return err
+			}
+			sent++
+		}
+	}
+	return rows.Err()
+}
+
+// queryRow es una fila con las columnas en el orden de la consulta
+type queryRow struct {
+	columns []string
+	values  []json.RawMessage
+}
+
+func (r queryRow) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, col := range r.columns {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(col)
+		buf.Write(name)
+		buf.WriteByte(':')
+		if i < len(r.values) {
+			buf.Write(r.values[i])
+		} else {
+			buf.WriteString("null")
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// csvValue convierte un valor guardado en texto para CSV (null = vacío)
+func csvValue(raw json.RawMessage) string {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return string(raw)
+	}
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	}
+	return string(raw)
+}
+
+// queryResultInfo retorna las columnas y la cantidad de filas del resultado de un job completado
+func queryResultInfo(job *AsyncJob) ([]string, int64) {
+	var columns []string
+	var count int64
+	switch cols := job.Result["columns"].(type) {
+	case []string:
+		columns = cols
+	case []interface{}: // Leído de ASYNC_JOBS
+		for _, c := range cols {
+			name, _ := c.(string)
+			columns = append(columns, name)
+		}
+	}
+	switch n := job.Result["row_count"].(type) {
+	case int64:
+		count = n
+	case float64:
+		count = int64(n)
+	}
+	return columns, count
+}
+
+// jobRowsHandler responde GET /jobs/{id}/rows: las filas de una consulta asíncrona terminada,
+// paginadas con offset y limit, o exportadas completas con format=json o format=csv
+func jobRowsHandler(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite GET"})
+		return
+	}
+	job, exists := jobManager.Lookup(id)
+	if !exists {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Job no encontrado"})
+		return
+	}
+	snap := job.snapshotLocked()
+	if _, ok := queryJobSQL(&snap); !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "El job no es una consulta (POST /query/async)"})
+		return
+	}
+	if snap.Status != JobStatusCompleted {
+		msg := "La consulta todavía no terminó"
+		if snap.Status.isFinal() {
+			msg = fmt.Sprintf("La consulta terminó con estado '%s' y no tiene filas", snap.Status)
+		}
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(map[string]string{"error": msg, "status": string(snap.Status)})
+		return
+	}
+
+	params := r.URL.Query()
+	var offset, limit int64
+	for name, dest := range map[string]*int64{"offset": &offset, "limit": &limit} {
+		if value := params.Get(name); value != "" {
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("'%s' debe ser un número positivo", name)})
+				return
+			}
+			*dest = n
+		}
+	}
+	columns, count := queryResultInfo(&snap)
+
+	switch format := strings.ToLower(params.Get("format")); format {
+	case "":
+		// Página en JSON
+	case "csv", "json":
+		// Exportación: todas las filas (o el rango de offset y limit)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, id, format))
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			exportQueryCSV(w, r, id, columns, offset, limit)
+		} else {
+			exportQueryJSON(w, r, id, columns, offset, limit)
+		}
+		return
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "'format' debe ser json o csv"})
+		return
+	}
+
+	if limit == 0 {
+		limit = queryRowsDefaultLimit
+	}
+	if limit > queryRowsMaxLimit {
+		limit = queryRowsMaxLimit
+	}
+	results := []queryRow{}
+	err := readQueryRows(r.Context(), id, offset, limit, func(row []json.RawMessage) error {
+		results = append(results, queryRow{columns: columns, values: row})
+		return nil
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Error leyendo el resultado: " + err.Error()})
+		return
+	}
+
+	hasMore := offset+int64(len(results)) < count
+	resp := map[string]interface{}{
+		"job_id":    id,
+		"columns":   columns,
+		"row_count": count,
+		"results":   results,
+		"offset":    offset,
+		"limit":     limit,
+		"has_more":  hasMore,
+	}
+	if hasMore {
+		resp["next_offset"] = offset + int64(len(results))
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// exportQueryCSV escribe las filas como CSV con una fila de encabezado
+func exportQueryCSV(w http.ResponseWriter, r *http.Request, id string, columns []string, offset, limit int64) {
+	cw := csv.NewWriter(w)
+	cw.Write(columns)
+	record := make([]string, len(columns))
+	err := readQueryRows(r.Context(), id, offset, limit, func(row []json.RawMessage) error {
+		for i := range record {
+			record[i] = ""
+			if i < len(row) {
+				record[i] = csvValue(row[i])
+			}
+		}
+		return cw.Write(record)
+	})
+	cw.Flush()
+	if err != nil {
+		// Los encabezados ya se enviaron: solo queda cortar la descarga
+		log.Printf("[QUERY] Error exportando el resultado del job %s: %v", id, err)
+	}
+}
+
+// exportQueryJSON escribe las filas como un arreglo JSON de objetos
+func exportQueryJSON(w http.ResponseWriter, r *http.Request, id string, columns []string, offset, limit int64) {
+	w.Write([]byte("["))
+	first := true
+	err := readQueryRows(r.Context(), id, offset, limit, func(row []json.RawMessage) error {
+		data, err := queryRow{columns: columns, values: row}.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		if !first {
+			w.Write([]byte(",\n"))
+		}
+		first = false
+		_, err = w.Write(data)
+		return err
+	})
+	w.Write([]byte("]\n"))
+	if err != nil {
+		log.Printf("[QUERY] Error exportando el resultado del job %s: %v", id, err)
+	}
+}
+
+// createQueryResultsTable crea ASYNC_QUERY_RESULTS si no existe (ver sql/create_async_query_results_table.sql).
+// Las filas se borran junto con su job (ON DELETE CASCADE).
+func createQueryResultsTable() error {
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM USER_TABLES WHERE TABLE_NAME = 'ASYNC_QUERY_RESULTS'").Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	log.Println("[QUERY] Creando tabla ASYNC_QUERY_RESULTS...")
+	_, err := db.Exec(`
+		CREATE TABLE ASYNC_QUERY_RESULTS (
+			JOB_ID VARCHAR2(32) NOT NULL REFERENCES ASYNC_JOBS(JOB_ID) ON DELETE CASCADE,
+			CHUNK_NO NUMBER NOT NULL,
+			FIRST_ROW NUMBER NOT NULL,
+			ROW_COUNT NUMBER NOT NULL,
+			DATA BLOB,
+			CONSTRAINT PK_ASYNC_QUERY_RESULTS PRIMARY KEY (JOB_ID, CHUNK_NO)
+		)`)
+	if err != nil {
+		return fmt.Errorf("error creando tabla ASYNC_QUERY_RESULTS: %v", err)
+	}
+	log.Println("[QUERY] Tabla ASYNC_QUERY_RESULTS creada")
+	return nil
+}
@@ -0,0 +1,459 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// restPackage es un paquete publicado como recurso REST (variable API_PACKAGES)
+type restPackage struct {
+	Schema string
+	Name   string
+}
+
+// configuredRESTPackages lee API_PACKAGES: lista separada por comas de PAQUETE o ESQUEMA.PAQUETE
+func configuredRESTPackages() []restPackage {
+	packages := []restPackage{}
+	for _, item := range strings.Split(os.Getenv("API_PACKAGES"), ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		schema, name := splitTypeName(item)
+		packages = append(packages, restPackage{Schema: schema, Name: name})
+	}
+	return packages
+}
+
+// findRESTPackage busca el paquete de la URL entre los publicados (sin distinguir mayúsculas)
+func findRESTPackage(name string) (restPackage, bool) {
+	for _, pkg := range configuredRESTPackages() {
+		if strings.EqualFold(pkg.Name, name) {
+			return pkg, true
+		}
+	}
+	return restPackage{}, false
+}
+
+// restReturnParam es la propiedad de la respuesta con el valor de retorno de las funciones
+const restReturnParam = "return"
+
+// restParamName es el nombre JSON de un argumento (en minúsculas)
+func restParamName(arg ProgramArgument) string {
+	return strings.ToLower(arg.Name)
+}
+
+// restRequestFor arma el ProcedureRequest para una sobrecarga a partir del cuerpo JSON.
+// Los argumentos IN se toman del cuerpo por nombre y todos los OUT se enlazan para la respuesta.
+func restRequestFor(sig *ProgramSignature, ov *ProgramOverload, body map[string]interface{}) *ProcedureRequest {
+	req := &ProcedureRequest{
+		Name:       sig.Package + "." + sig.Name,
+		Schema:     sig.Schema,
+		IsFunction: ov.Type == "FUNCTION",
+	}
+	if len(sig.Overloads) > 1 {
+		req.Overload = ov.Overload
+	}
+	if ov.Return != nil {
+		req.Params = append(req.Params, ProcedureParam{
+			Name: restReturnParam, Direction: "OUT", Type: ov.Return.Type, TypeName: ov.Return.TypeName,
+		})
+	}
+
+	known := make(map[string]bool, len(ov.Params))
+	for _, arg := range ov.Params {
+		known[strings.ToUpper(arg.Name)] = true
+		if arg.Direction == "OUT" {
+			req.Params = append(req.Params, ProcedureParam{
+				Name: arg.Name, Direction: "OUT", Type: arg.Type, TypeName: arg.TypeName,
+			})
+		}
+	}
+	// Las claves del cuerpo van tal cual para que la validación reporte las desconocidas
+	keys := make([]string, 0, len(body))
+	for key := range body {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		p := ProcedureParam{Name: key, Value: body[key]}
+		if known[strings.ToUpper(key)] {
+			for _, arg := range ov.Params {
+				if strings.EqualFold(arg.Name, key) && arg.TypeName != "" {
+					p.TypeName = arg.TypeName
+				}
+			}
+		}
+		req.Params = append(req.Params, p)
+	}
+	return req
+}
+
+// selectRESTOverload elige la sobrecarga cuyos argumentos coinciden con las claves del cuerpo
+func selectRESTOverload(sig *ProgramSignature, body map[string]interface{}) (*ProcedureRequest, error) {
+	type candidate struct {
+		req *ProcedureRequest
+		m   *overloadMatch
+	}
+	valid := []candidate{}
+	var last *overloadMatch
+	for i := range sig.Overloads {
+		req := restRequestFor(sig, &sig.Overloads[i], body)
+		m := matchOverload(req, &sig.Overloads[i])
+		if len(m.problems) == 0 {
+			valid = append(valid, candidate{req, m})
+		}
+		last = m
+	}
+
+	if len(valid) == 0 {
+		if len(sig.Overloads) == 1 {
+			return nil, &procedureError{Status: http.StatusBadRequest,
+				Msg:     fmt.Sprintf("Parámetros inválidos para '%s'", sig.FullName),
+				Details: last.problems}
+		}
+		return nil, &procedureError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(
+			"Ninguna sobrecarga de '%s' coincide con los parámetros enviados. Disponibles: %s", sig.FullName, describeOverloads(sig))}
+	}
+	sort.SliceStable(valid, func(i, j int) bool { return valid[i].m.exact > valid[j].m.exact })
+	if len(valid) > 1 && valid[0].m.exact == valid[1].m.exact {
+		return nil, &procedureError{Status: http.StatusBadRequest, Msg: fmt.Sprintf(
+			"Llamada ambigua a '%s': los parámetros coinciden con más de una sobrecarga. Disponibles: %s", sig.FullName, describeOverloads(sig))}
+	}
+	return valid[0].req, nil
+}
+
+// restSignature obtiene la firma de PAQUETE.PROCEDIMIENTO de un paquete publicado
+func restSignature(ctx context.Context, pkg restPackage, procedure string) (*ProgramSignature, error) {
+	return lookupSignature(ctx, pkg.Schema, pkg.Name+"."+strings.ToUpper(procedure))
+}
+
+// restAPIHandler expone los paquetes configurados en API_PACKAGES:
+// POST /api/{paquete}/{procedimiento} con un cuerpo JSON por nombre de parámetro, y GET /api/openapi.json
+func restAPIHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(&w, r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api"), "/")
+	if path == "openapi.json" {
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite GET"})
+			return
+		}
+		doc, err := buildOpenAPI(r.Context())
+		if err != nil {
+			log.Printf("[API] Error generando OpenAPI: %v", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(doc)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite POST"})
+		return
+	}
+	parts := strings.Split(path, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Ruta inválida: usa /api/{paquete}/{procedimiento}"})
+		return
+	}
+	pkg, ok := findRESTPackage(parts[0])
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Paquete '%s' no publicado", parts[0])})
+		return
+	}
+
+	// El cuerpo puede omitirse si el procedimiento no recibe parámetros IN
+	body := map[string]interface{}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
+		return
+	}
+
+	sig, err := restSignature(r.Context(), pkg, parts[1])
+	if err != nil {
+		log.Printf("[API] Error leyendo firma de %s.%s: %v", pkg.Name, parts[1], err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if sig == nil {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("Procedimiento '%s' no encontrado en %s", parts[1], pkg.Name)})
+		return
+	}
+
+	req, err := selectRESTOverload(sig, body)
+	if err != nil {
+		w.WriteHeader(procedureErrorStatus(err))
+		json.NewEncoder(w).Encode(procedureErrorBody(err))
+		return
+	}
+
+	startExec := time.Now()
+	bodyJSON, _ := json.Marshal(body)
+	qlog := &QueryLog{
+		ID:            generateID(),
+		QueryType:     "PROCEDURE",
+		QueryText:     sig.FullName,
+		Params:        string(bodyJSON),
+		ExecutionTime: startExec,
+		UserIP:        r.RemoteAddr,
+	}
+
+	result, err := executeProcedure(r.Context(), req, nil)
+	qlog.Duration = time.Since(startExec).String()
+	if err != nil {
+		qlog.Success = false
+		qlog.ErrorMsg = err.Error()
+		go saveQueryLog(qlog)
+		w.WriteHeader(procedureErrorStatus(err))
+		json.NewEncoder(w).Encode(procedureErrorBody(err))
+		return
+	}
+	qlog.Success = true
+	qlog.RowsAffected = int64(len(result.Out))
+	go saveQueryLog(qlog)
+
+	// La respuesta usa los mismos nombres (en minúsculas) que el cuerpo
+	resp := make(map[string]interface{}, len(result.Out))
+	for name, value := range result.Out {
+		resp[strings.ToLower(name)] = value
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// openAPISchemaFor describe un argumento PL/SQL como JSON Schema
+func openAPISchemaFor(arg *ProgramArgument) map[string]interface{} {
+	schema := map[string]interface{}{}
+	switch arg.Type {
+	case "number":
+		switch arg.DataType {
+		case "INTEGER", "BINARY_INTEGER", "PLS_INTEGER":
+			schema["type"] = "integer"
+		default:
+			schema["type"] = "number"
+		}
+	case "boolean":
+		schema["type"] = "boolean"
+	case "date":
+		schema["type"] = "string"
+		schema["description"] = "Fecha; entrada en " + strings.Join(getDateInputFormats(), ", ") + "; salida DD/MM/YYYY"
+	case "timestamp":
+		schema["type"] = "string"
+		schema["description"] = "Fecha y hora ISO-8601 sin zona horaria, con fracciones de segundo (2024-03-01T10:30:00.123456)"
+	case "timestamptz":
+		schema["type"] = "string"
+		schema["format"] = "date-time"
+		schema["description"] = "Fecha y hora ISO-8601 con offset (2024-03-01T10:30:00.123456-03:00)"
+	case "interval":
+		schema["type"] = "string"
+		schema["description"] = "Duración ISO-8601 (" + arg.DataType + "), p.ej. P1DT2H30M o P1Y2M"
+	case "string":
+		schema["type"] = "string"
+		if arg.Length > 0 && strings.Contains(arg.DataType, "CHAR") {
+			schema["maxLength"] = arg.Length
+		}
+	default:
+		if arg.TypeName == "" {
+			schema["description"] = fmt.Sprintf("Tipo %s no soportado", arg.DataType)
+			break
+		}
+		schema = openAPISchemaForUDT(arg.TypeName)
+	}
+	return schema
+}
+
+// openAPISchemaForUDT describe una colección u objeto leyendo su definición del diccionario
+func openAPISchemaForUDT(typeName string) map[string]interface{} {
+	t, err := udtTypes.Resolve(typeName)
+	if err != nil {
+		return map[string]interface{}{"description": fmt.Sprintf("%s (%v)", typeName, err)}
+	}
+	return openAPIUDTSchema(t)
+}
+
+func openAPIUDTSchema(t *udtType) map[string]interface{} {
+	if t.IsCollection {
+		var items map[string]interface{}
+		if t.Elem != nil {
+			items = openAPIUDTSchema(t.Elem)
+		} else {
+			items = openAPIScalarSchema(t.ElemType)
+		}
+		return map[string]interface{}{"type": "array", "items": items, "description": t.Owner + "." + t.Name}
+	}
+	props := map[string]interface{}{}
+	for _, attr := range t.Attrs {
+		if attr.Nested != nil {
+			props[attr.Name] = openAPIUDTSchema(attr.Nested)
+		} else {
+			props[attr.Name] = openAPIScalarSchema(attr.TypeName)
+		}
+	}
+	return map[string]interface{}{"type": "object", "properties": props, "description": t.Owner + "." + t.Name}
+}
+
+// openAPIScalarSchema describe un atributo o elemento escalar de un UDT
+func openAPIScalarSchema(typeName string) map[string]interface{} {
+	switch normalizeScalarType(typeName) {
+	case "NUMBER":
+		return map[string]interface{}{"type": "number"}
+	case "DATE", "TIMESTAMP":
+		return map[string]interface{}{"type": "string", "description": "Fecha"}
+	}
+	return map[string]interface{}{"type": "string"}
+}
+
+// openAPIOverloadSchemas arma los esquemas de request y response de una sobrecarga
+func openAPIOverloadSchemas(ov *ProgramOverload) (map[string]interface{}, map[string]interface{}) {
+	reqProps := map[string]interface{}{}
+	required := []string{}
+	respProps := map[string]interface{}{}
+	if ov.Return != nil {
+		respProps[restReturnParam] = openAPISchemaFor(ov.Return)
+	}
+	for i := range ov.Params {
+		arg := &ov.Params[i]
+		schema := openAPISchemaFor(arg)
+		if arg.Direction != "OUT" {
+			reqProps[restParamName(*arg)] = schema
+			if !arg.Defaulted {
+				required = append(required, restParamName(*arg))
+			}
+		}
+		if arg.Direction == "OUT" {
+			respProps[restParamName(*arg)] = schema
+		}
+	}
+	reqSchema := map[string]interface{}{"type": "object", "properties": reqProps}
+	if len(required) > 0 {
+		reqSchema["required"] = required
+	}
+	return reqSchema, map[string]interface{}{"type": "object", "properties": respProps}
+}
+
+// buildOpenAPI genera el documento OpenAPI 3 de los paquetes publicados
+func buildOpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	errorSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{"type": "string"},
+			"errors": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"param":   map[string]interface{}{"type": "string"},
+						"code":    map[string]interface{}{"type": "string"},
+						"message": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+	errorResponse := func(desc string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": desc,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"}},
+			},
+		}
+	}
+
+	paths := map[string]interface{}{}
+	for _, pkg := range configuredRESTPackages() {
+		programs, err := listPrograms(ctx, pkg.Schema, pkg.Name, "")
+		if err != nil {
+			return nil, fmt.Errorf("error listando %s: %v", pkg.Name, err)
+		}
+		seen := map[string]bool{}
+		for _, prog := range programs {
+			if seen[prog.Name] {
+				continue
+			}
+			seen[prog.Name] = true
+			sig, err := restSignature(ctx, pkg, prog.Name)
+			if err != nil {
+				return nil, fmt.Errorf("error leyendo firma de %s: %v", prog.FullName, err)
+			}
+			if sig == nil {
+				continue
+			}
+
+			reqSchemas := []interface{}{}
+			respSchemas := []interface{}{}
+			for i := range sig.Overloads {
+				reqSchema, respSchema := openAPIOverloadSchemas(&sig.Overloads[i])
+				reqSchemas = append(reqSchemas, reqSchema)
+				respSchemas = append(respSchemas, respSchema)
+			}
+			var reqSchema, respSchema interface{} = reqSchemas[0], respSchemas[0]
+			if len(reqSchemas) > 1 {
+				reqSchema = map[string]interface{}{"oneOf": reqSchemas}
+				respSchema = map[string]interface{}{"oneOf": respSchemas}
+			}
+
+			path := fmt.Sprintf("/api/%s/%s", strings.ToLower(pkg.Name), strings.ToLower(prog.Name))
+			paths[path] = map[string]interface{}{
+				"post": map[string]interface{}{
+					"operationId": strings.ToLower(pkg.Name + "_" + prog.Name),
+					"summary":     fmt.Sprintf("%s %s", sig.Overloads[0].Type, sig.FullName),
+					"tags":        []string{strings.ToLower(pkg.Name)},
+					"requestBody": map[string]interface{}{
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{"schema": reqSchema},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Valores OUT (y \"return\" en funciones)",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{"schema": respSchema},
+							},
+						},
+						"400": errorResponse("Parámetros inválidos"),
+						"404": errorResponse("Paquete o procedimiento no publicado"),
+						"500": errorResponse("Error de Oracle"),
+					},
+				},
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Go Oracle API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{"Error": errorSchema},
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Valores por defecto de la política de reintentos de /procedure/async
+const (
+	defaultRetryMaxAttempts = 3
+	maxRetryAttempts        = 10
+	defaultRetryBackoff     = 10 * time.Second
+	maxRetryBackoff         = 10 * time.Minute
+)
+
+// defaultRetryCodes son los errores transitorios que se reintentan si la política no indica retry_on:
+// deadlock, pérdida de la conexión y timeouts de conexión
+var defaultRetryCodes = []string{"ORA-00060", "ORA-03113", "ORA-03114", "ORA-03135", "ORA-12170"}
+
+// lostConnectionCode es el código con que se clasifican los errores de red del driver
+// (la conexión se cortó sin que Oracle enviara un ORA-)
+const lostConnectionCode = "ORA-03113"
+
+var oraCodePattern = regexp.MustCompile(`ORA-(\d{1,5})`)
+
+// RetryPolicy es la política de reintentos de un job ("retry" en /procedure/async)
+type RetryPolicy struct {
+	MaxAttempts int      `json:"max_attempts"`       // Intentos totales, incluido el primero
+	Backoff     string   `json:"backoff,omitempty"`  // Espera antes del primer reintento; se duplica en cada uno
+	RetryOn     []string `json:"retry_on,omitempty"` // Códigos ORA- que se reintentan
+}
+
+// JobAttempt es un intento fallido de un job
+type JobAttempt struct {
+	Attempt   int       `json:"attempt"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	Duration  string    `json:"duration"`
+	Error     string    `json:"error"`
+}
+
+// normalize completa los valores por defecto y valida la política
+func (p *RetryPolicy) normalize() error {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = defaultRetryMaxAttempts
+	}
+	if p.MaxAttempts < 1 || p.MaxAttempts > maxRetryAttempts {
+		return fmt.Errorf("'retry.max_attempts' debe estar entre 1 y %d", maxRetryAttempts)
+	}
+	if p.Backoff == "" {
+		p.Backoff = defaultRetryBackoff.String()
+	}
+	if d, err := time.ParseDuration(p.Backoff); err != nil || d <= 0 {
+		return fmt.Errorf("'retry.backoff' debe ser una duración positiva (p.ej. 10s, 1m)")
+	}
+	if len(p.RetryOn) == 0 {
+		p.RetryOn = append([]string(nil), defaultRetryCodes...)
+	}
+	for i, code := range p.RetryOn {
+		normalized, err := normalizeOraCode(code)
+		if err != nil {
+			return err
+		}
+		p.RetryOn[i] = normalized
+	}
+	return nil
+}
+
+// normalizeOraCode acepta "ORA-00060", "ORA-60", "00060" o "60" y retorna "ORA-00060"
+func normalizeOraCode(code string) (string, error) {
+	digits := strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(code)), "ORA-")
+	n, err := strconv.Atoi(digits)
+	if err != nil || n <= 0 || n > 99999 {
+		return "", fmt.Errorf("'retry.retry_on': código inválido '%s' (formato ORA-00060)", code)
+	}
+	return fmt.Sprintf("ORA-%05d", n), nil
+}
+
+// delay retorna la espera antes de ejecutar el intento indicado (2 en adelante)
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	delay, _ := time.ParseDuration(p.Backoff)
+	for i := 2; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	return delay
+}
+
+// retryable indica si el error de un intento se reintenta según la política
+func (p *RetryPolicy) retryable(err error) bool {
+	codes := oraCodes(err)
+	for _, code := range codes {
+		for _, allowed := range p.RetryOn {
+			if code == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// nextRetry indica si después del intento fallido attempt corresponde reintentar y con qué espera
+func (p *RetryPolicy) nextRetry(attempt int, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts || !p.retryable(err) {
+		return 0, false
+	}
+	return p.delay(attempt + 1), true
+}
+
+// oraCodes extrae los códigos ORA- de un error (incluido el error original del driver).
+// Los errores de red sin código se clasifican como pérdida de conexión (ORA-03113).
+func oraCodes(err error) []string {
+	text := err.Error()
+	var perr *procedureError
+	if errors.As(err, &perr) && perr.Err != nil {
+		text += "\n" + perr.Err.Error()
+	}
+	codes := []string{}
+	for _, m := range oraCodePattern.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.Atoi(m[1])
+		codes = append(codes, fmt.Sprintf("ORA-%05d", n))
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		codes = append(codes, lostConnectionCode)
+	}
+	return codes
+}
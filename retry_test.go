@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNormalizeOraCode(t *testing.T) {
+	for in, want := range map[string]string{
+		"ORA-00060": "ORA-00060",
+		"ora-60":    "ORA-00060",
+		"00060":     "ORA-00060",
+		" 3113 ":    "ORA-03113",
+		"ORA-99999": "ORA-99999",
+	} {
+		got, err := normalizeOraCode(in)
+		if err != nil || got != want {
+			t.Errorf("normalizeOraCode(%q) = %q, %v; se esperaba %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "ORA-", "ORA-0", "-5", "100000", "PLS-00306", "ORA-60x"} {
+		if got, err := normalizeOraCode(in); err == nil {
+			t.Errorf("normalizeOraCode(%q) = %q; se esperaba error", in, got)
+		}
+	}
+}
+
+func TestRetryPolicyNormalize(t *testing.T) {
+	p := &RetryPolicy{RetryOn: []string{"60", "ora-1013"}}
+	if err := p.normalize(); err != nil {
+		t.Fatal(err)
+	}
+	if p.MaxAttempts != defaultRetryMaxAttempts || p.Backoff != defaultRetryBackoff.String() {
+		t.Errorf("valores por defecto no aplicados: %+v", p)
+	}
+	if want := []string{"ORA-00060", "ORA-01013"}; !reflect.DeepEqual(p.RetryOn, want) {
+		t.Errorf("RetryOn = %v, se esperaba %v", p.RetryOn, want)
+	}
+
+	for _, bad := range []RetryPolicy{
+		{MaxAttempts: -1},
+		{MaxAttempts: maxRetryAttempts + 1},
+		{Backoff: "abc"},
+		{Backoff: "-1s"},
+		{RetryOn: []string{"x"}},
+	} {
+		if err := bad.normalize(); err == nil {
+			t.Errorf("normalize(%+v): se esperaba error", bad)
+		}
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := &RetryPolicy{Backoff: "10s"}
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{4, 40 * time.Second},
+		{7, 320 * time.Second},
+		{8, maxRetryBackoff},
+		{50, maxRetryBackoff},
+	}
+	for _, tt := range tests {
+		if got := p.delay(tt.attempt); got != tt.want {
+			t.Errorf("delay(%d) = %v, se esperaba %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestOraCodes(t *testing.T) {
+	tests := []struct {
+		err  error
+		want []string
+	}{
+		{errors.New("ORA-00060: deadlock detected while waiting for resource"), []string{"ORA-00060"}},
+		{errors.New("ORA-06550: line 1, column 7:\nPLS-00306: wrong number or types of arguments"), []string{"ORA-06550"}},
+		{errors.New("sin código"), []string{}},
+		{io.ErrUnexpectedEOF, []string{lostConnectionCode}},
+		{fmt.Errorf("leyendo: %w", io.EOF), []string{lostConnectionCode}},
+		// Se incluye el error original del driver aunque el mensaje sea otro
+		{&procedureError{Msg: "Error al ejecutar", Err: errors.New("ORA-01013: user requested cancel")}, []string{"ORA-01013"}},
+	}
+	for _, tt := range tests {
+		if got := oraCodes(tt.err); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("oraCodes(%q) = %v, se esperaba %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicyNextRetry(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 3, Backoff: "1s"}
+	if err := p.normalize(); err != nil {
+		t.Fatal(err)
+	}
+	deadlock := errors.New("ORA-00060: deadlock detected")
+
+	if d, ok := p.nextRetry(1, deadlock); !ok || d != time.Second {
+		t.Errorf("nextRetry(1) = %v, %v; se esperaba 1s, true", d, ok)
+	}
+	if d, ok := p.nextRetry(2, deadlock); !ok || d != 2*time.Second {
+		t.Errorf("nextRetry(2) = %v, %v; se esperaba 2s, true", d, ok)
+	}
+	if _, ok := p.nextRetry(3, deadlock); ok {
+		t.Error("nextRetry(3): no debería reintentar después del último intento")
+	}
+	if _, ok := p.nextRetry(1, errors.New("ORA-00001: unique constraint violated")); ok {
+		t.Error("nextRetry: ORA-00001 no está en retry_on")
+	}
+	var none *RetryPolicy
+	if _, ok := none.nextRetry(1, deadlock); ok {
+		t.Error("nextRetry sin política no debería reintentar")
+	}
+}
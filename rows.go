@@ -0,0 +1,65 @@
+package main
+
+import "database/sql"
+
+// resultSetMaxRows es el máximo de filas que se leen de cada result set implícito de /procedure
+const resultSetMaxRows = 5000
+
+// scanRowMaps lee las filas como mapas columna -> valor ([]byte se convierte a string).
+// Con limit > 0 deja de leer al llegar a esa cantidad de filas.
+func scanRowMaps(rows *sql.Rows, limit int) ([]map[string]interface{}, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	results := []map[string]interface{}{}
+	for rows.Next() {
+		columns := make([]interface{}, len(cols))
+		columnPointers := make([]interface{}, len(cols))
+		for i := range columns {
+			columnPointers[i] = &columns[i]
+		}
+		if err := rows.Scan(columnPointers...); err != nil {
+			return nil, err
+		}
+		rowMap := make(map[string]interface{})
+		for i, colName := range cols {
+			val := columns[i]
+			b, ok := val.([]byte)
+			if ok {
+				rowMap[colName] = string(b)
+			} else {
+				rowMap[colName] = val
+			}
+		}
+		results = append(results, rowMap)
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+	}
+	return results, rows.Err()
+}
+
+// readResultSets lee todos los result sets de la sentencia, p.ej. los implícitos que un
+// procedimiento retorna con DBMS_SQL.RETURN_RESULT. Los que no tienen columnas se omiten.
+// De cada uno se leen hasta limit filas; truncated indica que alguno tenía más.
+func readResultSets(rows *sql.Rows, limit int) (sets [][]map[string]interface{}, truncated bool, err error) {
+	sets = [][]map[string]interface{}{}
+	for {
+		if cols, err := rows.Columns(); err == nil && len(cols) > 0 {
+			results, err := scanRowMaps(rows, limit+1)
+			if err != nil {
+				return nil, false, err
+			}
+			if len(results) > limit {
+				results, truncated = results[:limit], true
+			}
+			sets = append(sets, results)
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return sets, truncated, rows.Err()
+}
@@ -0,0 +1,518 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Zonas horarias embebidas (Windows no trae la base de zonas de Go)
+)
+
+// scheduleTick es cada cuánto se revisan las programaciones vencidas
+const scheduleTick = 10 * time.Second
+
+// schedulePreviewRuns es la cantidad de próximas ejecuciones que se muestran por defecto
+const schedulePreviewRuns = 5
+
+// Schedule es una llamada a procedimiento que se encola como AsyncJob según una expresión cron
+type Schedule struct {
+	ID          string                 `json:"id"`
+	Description string                 `json:"description,omitempty"`
+	Cron        string                 `json:"cron"`
+	Timezone    string                 `json:"timezone"`
+	ProcName    string                 `json:"procedure_name"`
+	Params      map[string]interface{} `json:"params"` // Mismo formato que ASYNC_JOBS.PARAMS
+	Priority    int                    `json:"priority"`
+	Paused      bool                   `json:"paused"`
+	NextRun     *time.Time             `json:"next_run,omitempty"`
+	LastRun     *time.Time             `json:"last_run,omitempty"`
+	LastJobID   string                 `json:"last_job_id,omitempty"`
+	RunCount    int64                  `json:"run_count"`
+	CreatedAt   time.Time              `json:"created_at"`
+
+	cron *cronSchedule
+}
+
+// scheduleRequest es el cuerpo de POST /schedules: la llamada de /procedure más la programación
+type scheduleRequest struct {
+	ProcedureRequest
+	Cron        string `json:"cron"`
+	Timezone    string `json:"timezone,omitempty"` // Zona IANA (p.ej. America/Santiago); por defecto la del servidor
+	Priority    int    `json:"priority,omitempty"`
+	Description string `json:"description,omitempty"`
+	Paused      bool   `json:"paused,omitempty"`
+}
+
+// ScheduleManager mantiene las programaciones en memoria y las dispara desde ASYNC_SCHEDULES
+type ScheduleManager struct {
+	schedules map[string]*Schedule
+	mu        sync.Mutex
+}
+
+var scheduleManager = &ScheduleManager{
+	schedules: make(map[string]*Schedule),
+}
+
+// loadScheduleCron interpreta la zona y la expresión cron de una programación
+func loadScheduleCron(spec, timezone string) (*cronSchedule, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("zona horaria inválida '%s'", timezone)
+	}
+	return parseCron(spec, loc)
+}
+
+// nullableTime convierte un *time.Time en un valor para enlazar (NULL si no hay fecha)
+func nullableTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+// createSchedulesTable crea la tabla ASYNC_SCHEDULES si no existe
+func createSchedulesTable() error {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM USER_TABLES WHERE TABLE_NAME = 'ASYNC_SCHEDULES'").Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Println("[SCHEDULES] Tabla ASYNC_SCHEDULES ya existe")
+		return nil
+	}
+
+	log.Println("[SCHEDULES] Creando tabla ASYNC_SCHEDULES...")
+	_, err = db.Exec(`
+		CREATE TABLE ASYNC_SCHEDULES (
+			SCHEDULE_ID VARCHAR2(32) PRIMARY KEY,
+			DESCRIPTION VARCHAR2(400),
+			CRON_SPEC VARCHAR2(100) NOT NULL,
+			TIMEZONE VARCHAR2(64) NOT NULL,
+			PROCEDURE_NAME VARCHAR2(200) NOT NULL,
+			PARAMS CLOB,
+			PRIORITY NUMBER(2) DEFAULT 5 NOT NULL,
+			PAUSED NUMBER(1) DEFAULT 0 NOT NULL,
+			NEXT_RUN TIMESTAMP WITH TIME ZONE,
+			LAST_RUN TIMESTAMP WITH TIME ZONE,
+			LAST_JOB_ID VARCHAR2(32),
+			RUN_COUNT NUMBER DEFAULT 0 NOT NULL,
+			CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`)
+	if err != nil {
+		return fmt.Errorf("error creando tabla ASYNC_SCHEDULES: %v", err)
+	}
+	log.Println("[SCHEDULES] Tabla ASYNC_SCHEDULES creada exitosamente")
+	return nil
+}
+
+// scanSchedule lee una fila de ASYNC_SCHEDULES (columnas de scheduleColumns)
+func scanSchedule(row interface{ Scan(...interface{}) error }) (*Schedule, error) {
+	var s Schedule
+	var description, paramsJSON, lastJobID sql.NullString
+	var nextRun, lastRun sql.NullTime
+	var paused int
+	err := row.Scan(&s.ID, &description, &s.Cron, &s.Timezone, &s.ProcName, &paramsJSON,
+		&s.Priority, &paused, &nextRun, &lastRun, &lastJobID, &s.RunCount, &s.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.Description = description.String
+	s.LastJobID = lastJobID.String
+	s.Paused = paused == 1
+	if nextRun.Valid {
+		s.NextRun = &nextRun.Time
+	}
+	if lastRun.Valid {
+		s.LastRun = &lastRun.Time
+	}
+	if paramsJSON.Valid && paramsJSON.String != "" {
+		if err := json.Unmarshal([]byte(paramsJSON.String), &s.Params); err != nil {
+			return nil, fmt.Errorf("parámetros inválidos: %v", err)
+		}
+	}
+	if s.cron, err = loadScheduleCron(s.Cron, s.Timezone); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+const scheduleColumns = `SCHEDULE_ID, DESCRIPTION, CRON_SPEC, TIMEZONE, PROCEDURE_NAME, PARAMS,
+	PRIORITY, PAUSED, NEXT_RUN, LAST_RUN, LAST_JOB_ID, RUN_COUNT, CREATED_AT`
+
+// Load carga las programaciones desde ASYNC_SCHEDULES
+func (sm *ScheduleManager) Load() {
+	rows, err := db.Query("SELECT " + scheduleColumns + " FROM ASYNC_SCHEDULES")
+	if err != nil {
+		log.Printf("[SCHEDULES] No se pudieron cargar las programaciones: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			log.Printf("[SCHEDULES] Programación ignorada: %v", err)
+			continue
+		}
+		sm.schedules[s.ID] = s
+	}
+	if len(sm.schedules) > 0 {
+		log.Printf("[SCHEDULES] Cargadas %d programaciones desde Oracle", len(sm.schedules))
+	}
+}
+
+// reload vuelve a leer una programación de la BD (p.ej. si otra instancia la disparó)
+func (sm *ScheduleManager) reload(id string) {
+	s, err := scanSchedule(db.QueryRow("SELECT "+scheduleColumns+" FROM ASYNC_SCHEDULES WHERE SCHEDULE_ID = :1", id))
+	if err == sql.ErrNoRows {
+		delete(sm.schedules, id)
+		return
+	}
+	if err != nil {
+		log.Printf("[SCHEDULES] Error recargando %s: %v", id, err)
+		return
+	}
+	sm.schedules[id] = s
+}
+
+// Create registra una programación nueva
+func (sm *ScheduleManager) Create(s *Schedule) error {
+	paramsJSON, _ := json.Marshal(s.Params)
+	paused := 0
+	if s.Paused {
+		paused = 1
+	}
+	_, err := db.Exec(`
+		INSERT INTO ASYNC_SCHEDULES (
+			SCHEDULE_ID, DESCRIPTION, CRON_SPEC, TIMEZONE, PROCEDURE_NAME, PARAMS,
+			PRIORITY, PAUSED, NEXT_RUN, RUN_COUNT, CREATED_AT
+		) VALUES (:1, :2, :3, :4, :5, :6, :7, :8, :9, 0, :10)`,
+		s.ID, s.Description, s.Cron, s.Timezone, s.ProcName, string(paramsJSON),
+		s.Priority, paused, nullableTime(s.NextRun), s.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error guardando programación: %v", err)
+	}
+	sm.mu.Lock()
+	sm.schedules[s.ID] = s
+	sm.mu.Unlock()
+	return nil
+}
+
+// Get retorna una copia de la programación
+func (sm *ScheduleManager) Get(id string) (Schedule, bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	s, ok := sm.schedules[id]
+	if !ok {
+		return Schedule{}, false
+	}
+	return *s, true
+}
+
+// List retorna copias de las programaciones ordenadas por próxima ejecución
+func (sm *ScheduleManager) List() []Schedule {
+	sm.mu.Lock()
+	list := make([]Schedule, 0, len(sm.schedules))
+	for _, s := range sm.schedules {
+		list = append(list, *s)
+	}
+	sm.mu.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].NextRun == nil || list[j].NextRun == nil {
+			return list[j].NextRun == nil && list[i].NextRun != nil
+		}
+		return list[i].NextRun.Before(*list[j].NextRun)
+	})
+	return list
+}
+
+// Delete elimina la programación (los jobs ya creados no se tocan)
+func (sm *ScheduleManager) Delete(id string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, ok := sm.schedules[id]; !ok {
+		return fmt.Errorf("programación no encontrada")
+	}
+	if _, err := db.Exec("DELETE FROM ASYNC_SCHEDULES WHERE SCHEDULE_ID = :1", id); err != nil {
+		return err
+	}
+	delete(sm.schedules, id)
+	return nil
+}
+
+// SetPaused pausa o reanuda una programación. Al reanudar la próxima ejecución se calcula desde
+// ahora: las ejecuciones que cayeron durante la pausa no se recuperan.
+func (sm *ScheduleManager) SetPaused(id string, paused bool) (Schedule, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	s, ok := sm.schedules[id]
+	if !ok {
+		return Schedule{}, fmt.Errorf("programación no encontrada")
+	}
+	nextRun := s.NextRun
+	if !paused {
+		if next := s.cron.Next(time.Now()); !next.IsZero() {
+			nextRun = &next
+		} else {
+			nextRun = nil
+		}
+	}
+	flag := 0
+	if paused {
+		flag = 1
+	}
+	if _, err := db.Exec("UPDATE ASYNC_SCHEDULES SET PAUSED = :1, NEXT_RUN = :2 WHERE SCHEDULE_ID = :3",
+		flag, nullableTime(nextRun), id); err != nil {
+		return Schedule{}, err
+	}
+	s.Paused = paused
+	s.NextRun = nextRun
+	return *s, nil
+}
+
+// Start lanza el ciclo que dispara las programaciones vencidas
+func (sm *ScheduleManager) Start() {
+	go func() {
+		ticker := time.NewTicker(scheduleTick)
+		defer ticker.Stop()
+		for now := range ticker.C {
+			sm.fireDue(now)
+		}
+	}()
+}
+
+// fireDue encola un job por cada programación vencida
+func (sm *ScheduleManager) fireDue(now time.Time) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for _, s := range sm.schedules {
+		if !s.Paused && s.NextRun != nil && !s.NextRun.After(now) {
+			sm.fire(s, now)
+		}
+	}
+}
+
+// fire reserva la ejecución en ASYNC_SCHEDULES y crea el job. La reserva incrementa RUN_COUNT
+// solo si todavía vale lo que se leyó, así una misma ejecución no se dispara dos veces aunque
+// la API se reinicie o haya otra instancia usando la misma tabla. Si la API estuvo detenida
+// durante varias ejecuciones, se dispara una sola vez y se sigue desde ahora.
+func (sm *ScheduleManager) fire(s *Schedule, now time.Time) {
+	fireTime := *s.NextRun
+	var nextRun *time.Time
+	if next := s.cron.Next(now); !next.IsZero() {
+		nextRun = &next
+	}
+
+	res, err := db.Exec(`
+		UPDATE ASYNC_SCHEDULES SET RUN_COUNT = RUN_COUNT + 1, LAST_RUN = :1, NEXT_RUN = :2
+		WHERE SCHEDULE_ID = :3 AND RUN_COUNT = :4 AND PAUSED = 0`,
+		fireTime.UTC(), nullableTime(nextRun), s.ID, s.RunCount)
+	if err != nil {
+		log.Printf("[SCHEDULES] Error reservando ejecución de %s: %v", s.ID, err)
+		return
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		// Otra instancia ya la disparó o se pausó/eliminó: tomar el estado de la BD
+		sm.reload(s.ID)
+		return
+	}
+	s.RunCount++
+	s.LastRun = &fireTime
+	s.NextRun = nextRun
+	if now.Sub(fireTime) > 2*scheduleTick {
+		log.Printf("[SCHEDULES] %s: ejecución de %s disparada con atraso", s.ID, fireTime.Format(time.RFC3339))
+	}
+
+	params := make(map[string]interface{}, len(s.Params)+1)
+	for k, v := range s.Params {
+		params[k] = v
+	}
+	params["schedule_id"] = s.ID
+	req, err := procedureRequestFromParams(s.Params)
+	if err != nil {
+		log.Printf("[SCHEDULES] %s: parámetros inválidos: %v", s.ID, err)
+		return
+	}
+	job, err := submitProcedureJob(req, params, jobOptions{Priority: s.Priority, SubmittedBy: "schedule:" + s.ID})
+	if err != nil {
+		log.Printf("[SCHEDULES] %s: no se pudo encolar el job %s: %v", s.ID, job.ID, err)
+	} else {
+		log.Printf("[SCHEDULES] %s: job %s encolado (%s)", s.ID, job.ID, s.ProcName)
+	}
+	s.LastJobID = job.ID
+	if _, err := db.Exec("UPDATE ASYNC_SCHEDULES SET LAST_JOB_ID = :1 WHERE SCHEDULE_ID = :2", job.ID, s.ID); err != nil {
+		log.Printf("[SCHEDULES] Error guardando último job de %s: %v", s.ID, err)
+	}
+}
+
+// scheduleView agrega a la programación la vista previa de sus próximas ejecuciones
+func scheduleView(s Schedule, count int) map[string]interface{} {
+	view := map[string]interface{}{"schedule": s}
+	if !s.Paused {
+		view["next_runs"] = s.cron.NextRuns(time.Now(), count)
+	}
+	return view
+}
+
+// previewCount lee ?count= (por defecto 5, máximo 50)
+func previewCount(r *http.Request) int {
+	count, err := strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count < 1 {
+		return schedulePreviewRuns
+	}
+	if count > 50 {
+		return 50
+	}
+	return count
+}
+
+// schedulesHandler maneja /schedules, /schedules/preview, /schedules/{id} y /schedules/{id}/pause|resume
+func schedulesHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(&w, r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/schedules"), "/")
+	switch {
+	case path == "":
+		if r.Method == http.MethodPost {
+			createScheduleHandler(w, r)
+			return
+		}
+		if r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite GET o POST"})
+			return
+		}
+		list := scheduleManager.List()
+		json.NewEncoder(w).Encode(map[string]interface{}{"total": len(list), "schedules": list})
+
+	case path == "preview":
+		// GET /schedules/preview?cron=0 2 * * *&timezone=America/Santiago&count=5
+		timezone := r.URL.Query().Get("timezone")
+		if timezone == "" {
+			timezone = "Local"
+		}
+		c, err := loadScheduleCron(r.URL.Query().Get("cron"), timezone)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"cron":      r.URL.Query().Get("cron"),
+			"timezone":  timezone,
+			"next_runs": c.NextRuns(time.Now(), previewCount(r)),
+		})
+
+	case strings.HasSuffix(path, "/pause") || strings.HasSuffix(path, "/resume"):
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite POST"})
+			return
+		}
+		id, action, _ := strings.Cut(path, "/")
+		s, err := scheduleManager.SetPaused(id, action == "pause")
+		if err != nil {
+			status := http.StatusInternalServerError
+			if _, ok := scheduleManager.Get(id); !ok {
+				status = http.StatusNotFound
+			}
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		json.NewEncoder(w).Encode(scheduleView(s, schedulePreviewRuns))
+
+	default:
+		switch r.Method {
+		case http.MethodGet:
+			s, ok := scheduleManager.Get(path)
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Programación no encontrada"})
+				return
+			}
+			json.NewEncoder(w).Encode(scheduleView(s, previewCount(r)))
+		case http.MethodDelete:
+			if err := scheduleManager.Delete(path); err != nil {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"message": "Programación eliminada", "id": path})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite GET o DELETE"})
+		}
+	}
+}
+
+// createScheduleHandler valida la llamada igual que /procedure/async y registra la programación
+func createScheduleHandler(w http.ResponseWriter, r *http.Request) {
+	var body scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
+		return
+	}
+	if body.Timezone == "" {
+		body.Timezone = "Local"
+	}
+	c, err := loadScheduleCron(body.Cron, body.Timezone)
+	if err == nil && c.Next(time.Now()).IsZero() {
+		err = fmt.Errorf("la expresión cron '%s' no tiene ejecuciones futuras", body.Cron)
+	}
+	var priority int
+	if err == nil {
+		priority, err = jobPriority(body.Priority)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	req := body.ProcedureRequest
+	if err := prepareProcedureRequest(r.Context(), &req); err != nil {
+		w.WriteHeader(procedureErrorStatus(err))
+		json.NewEncoder(w).Encode(procedureErrorBody(err))
+		return
+	}
+
+	next := c.Next(time.Now())
+	s := &Schedule{
+		ID:          generateJobID(),
+		Description: body.Description,
+		Cron:        body.Cron,
+		Timezone:    body.Timezone,
+		ProcName:    req.Name,
+		Params:      procedureRequestToParams(&req),
+		Priority:    priority,
+		Paused:      body.Paused,
+		NextRun:     &next,
+		CreatedAt:   time.Now(),
+		cron:        c,
+	}
+	if err := scheduleManager.Create(s); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	log.Printf("[SCHEDULES] Programación %s creada: %s '%s' (%s)", s.ID, s.ProcName, s.Cron, s.Timezone)
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(scheduleView(*s, schedulePreviewRuns))
+}
@@ -18,7 +18,7 @@ END;
 -- Crear tabla de jobs con validaciones
 CREATE TABLE ASYNC_JOBS (
     JOB_ID VARCHAR2(32) PRIMARY KEY,
-    STATUS VARCHAR2(20) NOT NULL CHECK (STATUS IN ('pending', 'running', 'completed', 'failed')),
+    STATUS VARCHAR2(20) NOT NULL CONSTRAINT CHK_ASYNC_JOBS_STATUS CHECK (STATUS IN ('pending', 'running', 'completed', 'failed', 'cancelled', 'interrupted')),
     PROCEDURE_NAME VARCHAR2(200) NOT NULL,
     PARAMS CLOB,
     START_TIME TIMESTAMP NOT NULL,
@@ -27,6 +27,23 @@ CREATE TABLE ASYNC_JOBS (
     RESULT CLOB,
     ERROR_MSG CLOB,
     PROGRESS NUMBER DEFAULT 0 CHECK (PROGRESS BETWEEN 0 AND 100),
+    PRIORITY NUMBER(2) DEFAULT 5 NOT NULL CHECK (PRIORITY BETWEEN 1 AND 10),
+    CALLBACK_URL VARCHAR2(2000),
+    WEBHOOK CLOB,
+    ATTEMPT NUMBER(3) DEFAULT 0,
+    RETRY_POLICY VARCHAR2(1000),
+    ATTEMPTS CLOB,
+    NEXT_RETRY TIMESTAMP,
+    PARENT_ID VARCHAR2(32),
+    STEP_ID VARCHAR2(100),
+    WORKFLOW CLOB,
+    INSTANCE_NAME VARCHAR2(100),
+    HEARTBEAT TIMESTAMP,
+    ON_INTERRUPT VARCHAR2(10),
+    CANCEL_REQUESTED TIMESTAMP,
+    PROGRESS_MESSAGE VARCHAR2(4000),
+    SCHEMA_NAME VARCHAR2(128),
+    SUBMITTED_BY VARCHAR2(200),
     CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
@@ -34,11 +51,13 @@ CREATE TABLE ASYNC_JOBS (
 CREATE INDEX IDX_ASYNC_JOBS_STATUS ON ASYNC_JOBS(STATUS);
 CREATE INDEX IDX_ASYNC_JOBS_START_TIME ON ASYNC_JOBS(START_TIME);
 CREATE INDEX IDX_ASYNC_JOBS_CREATED_AT ON ASYNC_JOBS(CREATED_AT);
+CREATE INDEX IDX_ASYNC_JOBS_PARENT_ID ON ASYNC_JOBS(PARENT_ID);
+CREATE INDEX IDX_ASYNC_JOBS_INSTANCE ON ASYNC_JOBS(INSTANCE_NAME, STATUS);
 
 -- Comentarios en columnas
 COMMENT ON TABLE ASYNC_JOBS IS 'Almacena información de jobs asíncronos ejecutados por la API';
 COMMENT ON COLUMN ASYNC_JOBS.JOB_ID IS 'ID único del job (32 caracteres hex)';
-COMMENT ON COLUMN ASYNC_JOBS.STATUS IS 'Estado del job: pending, running, completed, failed';
+COMMENT ON COLUMN ASYNC_JOBS.STATUS IS 'Estado del job: pending, running, completed, failed, cancelled, interrupted';
 COMMENT ON COLUMN ASYNC_JOBS.PROCEDURE_NAME IS 'Nombre del procedimiento ejecutado';
 COMMENT ON COLUMN ASYNC_JOBS.PARAMS IS 'Parámetros del procedimiento en formato JSON';
 COMMENT ON COLUMN ASYNC_JOBS.START_TIME IS 'Hora de inicio del job';
@@ -47,6 +66,23 @@ COMMENT ON COLUMN ASYNC_JOBS.DURATION IS 'Duración de la ejecución (formato le
 COMMENT ON COLUMN ASYNC_JOBS.RESULT IS 'Resultado de la ejecución en formato JSON';
 COMMENT ON COLUMN ASYNC_JOBS.ERROR_MSG IS 'Mensaje de error si el job falló';
 COMMENT ON COLUMN ASYNC_JOBS.PROGRESS IS 'Progreso del job (0-100)';
+COMMENT ON COLUMN ASYNC_JOBS.PRIORITY IS 'Prioridad del job: 1 (baja) a 10 (alta)';
+COMMENT ON COLUMN ASYNC_JOBS.CALLBACK_URL IS 'URL que recibe el job final por POST (webhook)';
+COMMENT ON COLUMN ASYNC_JOBS.WEBHOOK IS 'Estado e intentos de entrega del webhook en formato JSON';
+COMMENT ON COLUMN ASYNC_JOBS.ATTEMPT IS 'Intento en curso o último ejecutado (1 = primero)';
+COMMENT ON COLUMN ASYNC_JOBS.RETRY_POLICY IS 'Política de reintentos en formato JSON (max_attempts, backoff, retry_on)';
+COMMENT ON COLUMN ASYNC_JOBS.ATTEMPTS IS 'Intentos fallidos en formato JSON';
+COMMENT ON COLUMN ASYNC_JOBS.NEXT_RETRY IS 'Hora del próximo reintento mientras el job espera su backoff';
+COMMENT ON COLUMN ASYNC_JOBS.PARENT_ID IS 'Job padre del workflow al que pertenece el paso';
+COMMENT ON COLUMN ASYNC_JOBS.STEP_ID IS 'ID del paso del workflow que ejecuta el job';
+COMMENT ON COLUMN ASYNC_JOBS.WORKFLOW IS 'Estado de los pasos del workflow en formato JSON (job padre)';
+COMMENT ON COLUMN ASYNC_JOBS.INSTANCE_NAME IS 'Instancia de la API dueña del job';
+COMMENT ON COLUMN ASYNC_JOBS.HEARTBEAT IS 'Última señal de vida de la instancia dueña mientras el job no termina';
+COMMENT ON COLUMN ASYNC_JOBS.ON_INTERRUPT IS 'Política si la instancia se detiene durante la ejecución: fail o requeue';
+COMMENT ON COLUMN ASYNC_JOBS.CANCEL_REQUESTED IS 'Cancelación pedida desde otra instancia; la procesa la instancia dueña';
+COMMENT ON COLUMN ASYNC_JOBS.PROGRESS_MESSAGE IS 'Último mensaje de avance informado por el procedimiento';
+COMMENT ON COLUMN ASYNC_JOBS.SCHEMA_NAME IS 'Esquema del procedimiento indicado al crear el job';
+COMMENT ON COLUMN ASYNC_JOBS.SUBMITTED_BY IS 'Quién creó el job: IP del cliente o schedule:<id>';
 COMMENT ON COLUMN ASYNC_JOBS.CREATED_AT IS 'Timestamp de creación del registro';
 
 -- Procedimiento de limpieza de jobs antiguos
@@ -57,7 +93,7 @@ CREATE OR REPLACE PROCEDURE CLEANUP_OLD_ASYNC_JOBS (
 BEGIN
     DELETE FROM ASYNC_JOBS
     WHERE START_TIME < SYSDATE - p_days_old
-    AND STATUS IN ('completed', 'failed');
+    AND STATUS IN ('completed', 'failed', 'cancelled', 'interrupted');
     
     p_deleted_count := SQL%ROWCOUNT;
     COMMIT;
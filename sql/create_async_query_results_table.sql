@@ -0,0 +1,39 @@
+-- ==============================================================================
+-- Script de creación de tabla ASYNC_QUERY_RESULTS
+-- Propósito: Almacenar las filas de las consultas asíncronas (POST /query/async)
+-- Requiere ASYNC_JOBS (sql/create_async_jobs_table.sql); la API la crea al iniciar si no existe
+-- ==============================================================================
+
+-- Eliminar tabla si existe (solo para desarrollo/reinstalación)
+BEGIN
+    EXECUTE IMMEDIATE 'DROP TABLE ASYNC_QUERY_RESULTS CASCADE CONSTRAINTS';
+    DBMS_OUTPUT.PUT_LINE('Tabla ASYNC_QUERY_RESULTS eliminada');
+EXCEPTION
+    WHEN OTHERS THEN
+        IF SQLCODE != -942 THEN -- -942 = table does not exist
+            RAISE;
+        END IF;
+END;
+/
+
+-- Crear tabla de resultados: cada fila es un bloque de hasta 1000 filas de la consulta
+CREATE TABLE ASYNC_QUERY_RESULTS (
+    JOB_ID VARCHAR2(32) NOT NULL REFERENCES ASYNC_JOBS(JOB_ID) ON DELETE CASCADE,
+    CHUNK_NO NUMBER NOT NULL,
+    FIRST_ROW NUMBER NOT NULL,
+    ROW_COUNT NUMBER NOT NULL,
+    DATA BLOB,
+    CONSTRAINT PK_ASYNC_QUERY_RESULTS PRIMARY KEY (JOB_ID, CHUNK_NO)
+);
+
+-- Comentarios en columnas
+COMMENT ON TABLE ASYNC_QUERY_RESULTS IS 'Filas de las consultas asíncronas; se borran junto con su job';
+COMMENT ON COLUMN ASYNC_QUERY_RESULTS.JOB_ID IS 'ID del job (ASYNC_JOBS.JOB_ID)';
+COMMENT ON COLUMN ASYNC_QUERY_RESULTS.CHUNK_NO IS 'Número de bloque (0 = primero)';
+COMMENT ON COLUMN ASYNC_QUERY_RESULTS.FIRST_ROW IS 'Posición de la primera fila del bloque en el resultado (0 = primera)';
+COMMENT ON COLUMN ASYNC_QUERY_RESULTS.ROW_COUNT IS 'Filas del bloque';
+COMMENT ON COLUMN ASYNC_QUERY_RESULTS.DATA IS 'Filas del bloque en formato JSON (arreglo de arreglos, en el orden de las columnas)';
+
+PROMPT ========================================
+PROMPT Tabla ASYNC_QUERY_RESULTS creada exitosamente
+PROMPT ========================================
@@ -0,0 +1,53 @@
+-- ==============================================================================
+-- Script de creación de tabla ASYNC_SCHEDULES
+-- Propósito: Almacenar las ejecuciones programadas (cron) de procedimientos
+-- ==============================================================================
+
+-- Eliminar tabla si existe (solo para desarrollo/reinstalación)
+BEGIN
+    EXECUTE IMMEDIATE 'DROP TABLE ASYNC_SCHEDULES CASCADE CONSTRAINTS';
+    DBMS_OUTPUT.PUT_LINE('Tabla ASYNC_SCHEDULES eliminada');
+EXCEPTION
+    WHEN OTHERS THEN
+        IF SQLCODE != -942 THEN -- -942 = table does not exist
+            RAISE;
+        END IF;
+END;
+/
+
+-- Crear tabla de programaciones
+CREATE TABLE ASYNC_SCHEDULES (
+    SCHEDULE_ID VARCHAR2(32) PRIMARY KEY,
+    DESCRIPTION VARCHAR2(400),
+    CRON_SPEC VARCHAR2(100) NOT NULL,
+    TIMEZONE VARCHAR2(64) NOT NULL,
+    PROCEDURE_NAME VARCHAR2(200) NOT NULL,
+    PARAMS CLOB,
+    PRIORITY NUMBER(2) DEFAULT 5 NOT NULL CHECK (PRIORITY BETWEEN 1 AND 10),
+    PAUSED NUMBER(1) DEFAULT 0 NOT NULL CHECK (PAUSED IN (0, 1)),
+    NEXT_RUN TIMESTAMP WITH TIME ZONE,
+    LAST_RUN TIMESTAMP WITH TIME ZONE,
+    LAST_JOB_ID VARCHAR2(32),
+    RUN_COUNT NUMBER DEFAULT 0 NOT NULL,
+    CREATED_AT TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Comentarios en columnas
+COMMENT ON TABLE ASYNC_SCHEDULES IS 'Ejecuciones programadas (cron) que la API encola como jobs asíncronos';
+COMMENT ON COLUMN ASYNC_SCHEDULES.SCHEDULE_ID IS 'ID único de la programación (32 caracteres hex)';
+COMMENT ON COLUMN ASYNC_SCHEDULES.DESCRIPTION IS 'Descripción libre';
+COMMENT ON COLUMN ASYNC_SCHEDULES.CRON_SPEC IS 'Expresión cron de 5 campos (minuto hora día-mes mes día-semana) o @daily, @hourly, ...';
+COMMENT ON COLUMN ASYNC_SCHEDULES.TIMEZONE IS 'Zona horaria IANA en la que se evalúa la expresión (Local = la del servidor)';
+COMMENT ON COLUMN ASYNC_SCHEDULES.PROCEDURE_NAME IS 'Nombre del procedimiento a ejecutar';
+COMMENT ON COLUMN ASYNC_SCHEDULES.PARAMS IS 'Llamada en formato JSON (mismo formato que ASYNC_JOBS.PARAMS)';
+COMMENT ON COLUMN ASYNC_SCHEDULES.PRIORITY IS 'Prioridad de los jobs creados: 1 (baja) a 10 (alta)';
+COMMENT ON COLUMN ASYNC_SCHEDULES.PAUSED IS '1 si la programación está pausada';
+COMMENT ON COLUMN ASYNC_SCHEDULES.NEXT_RUN IS 'Próxima ejecución';
+COMMENT ON COLUMN ASYNC_SCHEDULES.LAST_RUN IS 'Última ejecución disparada';
+COMMENT ON COLUMN ASYNC_SCHEDULES.LAST_JOB_ID IS 'ID del último job creado (ASYNC_JOBS.JOB_ID)';
+COMMENT ON COLUMN ASYNC_SCHEDULES.RUN_COUNT IS 'Ejecuciones disparadas; se usa para evitar disparos dobles';
+COMMENT ON COLUMN ASYNC_SCHEDULES.CREATED_AT IS 'Timestamp de creación del registro';
+
+PROMPT ========================================
+PROMPT Tabla ASYNC_SCHEDULES creada exitosamente
+PROMPT ========================================
@@ -0,0 +1,79 @@
+-- ==============================================================================
+-- Script de creación de tabla ASYNC_JOB_PROGRESS y paquete API_JOB_PROGRESS
+-- Propósito: Que los procedimientos ejecutados como jobs asíncronos informen su avance
+-- La API los crea al iniciar si no existen
+-- ==============================================================================
+
+-- Eliminar tabla si existe (solo para desarrollo/reinstalación)
+BEGIN
+    EXECUTE IMMEDIATE 'DROP TABLE ASYNC_JOB_PROGRESS CASCADE CONSTRAINTS';
+    DBMS_OUTPUT.PUT_LINE('Tabla ASYNC_JOB_PROGRESS eliminada');
+EXCEPTION
+    WHEN OTHERS THEN
+        IF SQLCODE != -942 THEN -- -942 = table does not exist
+            RAISE;
+        END IF;
+END;
+/
+
+-- Avance en curso de cada job (la API borra la fila cuando la llamada termina)
+CREATE TABLE ASYNC_JOB_PROGRESS (
+    JOB_ID VARCHAR2(32) PRIMARY KEY,
+    PERCENT NUMBER(5,2),
+    MESSAGE VARCHAR2(4000),
+    UPDATED_AT TIMESTAMP DEFAULT SYSTIMESTAMP
+);
+
+COMMENT ON TABLE ASYNC_JOB_PROGRESS IS 'Avance informado por los procedimientos en ejecución como jobs asíncronos';
+COMMENT ON COLUMN ASYNC_JOB_PROGRESS.JOB_ID IS 'ID del job (ASYNC_JOBS.JOB_ID)';
+COMMENT ON COLUMN ASYNC_JOB_PROGRESS.PERCENT IS 'Avance informado (0-100)';
+COMMENT ON COLUMN ASYNC_JOB_PROGRESS.MESSAGE IS 'Mensaje de avance (progress_message del job)';
+COMMENT ON COLUMN ASYNC_JOB_PROGRESS.UPDATED_AT IS 'Última actualización';
+
+-- La API marca la sesión del job con CLIENT_INFO = 'job:<id>'
+CREATE OR REPLACE PACKAGE API_JOB_PROGRESS AS
+    -- ID del job que ejecuta la sesión (NULL fuera de un job de la API)
+    FUNCTION job_id RETURN VARCHAR2;
+    -- Informa el avance (0-100) y un mensaje; no hace nada fuera de un job
+    PROCEDURE report(p_percent IN NUMBER, p_message IN VARCHAR2 DEFAULT NULL);
+END API_JOB_PROGRESS;
+/
+
+CREATE OR REPLACE PACKAGE BODY API_JOB_PROGRESS AS
+    FUNCTION job_id RETURN VARCHAR2 IS
+        v_info VARCHAR2(64) := SYS_CONTEXT('USERENV', 'CLIENT_INFO');
+    BEGIN
+        IF v_info LIKE 'job:%' THEN
+            RETURN SUBSTR(v_info, 5);
+        END IF;
+        RETURN NULL;
+    END job_id;
+
+    -- Transacción autónoma: el avance se ve aunque el procedimiento no haya hecho COMMIT.
+    -- El avance se acota a 0-100 y un error al guardarlo nunca llega al procedimiento.
+    PROCEDURE report(p_percent IN NUMBER, p_message IN VARCHAR2 DEFAULT NULL) IS
+        PRAGMA AUTONOMOUS_TRANSACTION;
+        v_job_id VARCHAR2(32) := job_id;
+        v_percent NUMBER;
+        v_message VARCHAR2(4000);
+    BEGIN
+        IF v_job_id IS NULL THEN
+            RETURN;
+        END IF;
+        v_percent := GREATEST(0, LEAST(100, p_percent));
+        v_message := SUBSTRB(p_message, 1, 4000);
+        MERGE INTO ASYNC_JOB_PROGRESS p
+        USING (SELECT v_job_id AS JOB_ID FROM DUAL) s ON (p.JOB_ID = s.JOB_ID)
+        WHEN MATCHED THEN UPDATE SET PERCENT = v_percent, MESSAGE = v_message, UPDATED_AT = SYSTIMESTAMP
+        WHEN NOT MATCHED THEN INSERT (JOB_ID, PERCENT, MESSAGE) VALUES (v_job_id, v_percent, v_message);
+        COMMIT;
+    EXCEPTION
+        WHEN OTHERS THEN
+            ROLLBACK;
+    END report;
+END API_JOB_PROGRESS;
+/
+
+PROMPT ========================================
+PROMPT ASYNC_JOB_PROGRESS y API_JOB_PROGRESS creados exitosamente
+PROMPT ========================================
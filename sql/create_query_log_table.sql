@@ -1,7 +1,7 @@
 -- Tabla para registrar todas las consultas ejecutadas
 CREATE TABLE QUERY_LOG (
     LOG_ID VARCHAR2(32) PRIMARY KEY,
-    QUERY_TYPE VARCHAR2(20) NOT NULL,  -- 'QUERY', 'EXEC', 'PROCEDURE'
+    QUERY_TYPE VARCHAR2(20) NOT NULL,  -- 'QUERY', 'EXEC', 'PROCEDURE', 'TABLE_FUNCTION'
     QUERY_TEXT CLOB NOT NULL,
     PARAMS CLOB,
     EXECUTION_TIME TIMESTAMP NOT NULL,
@@ -22,7 +22,7 @@ CREATE INDEX IDX_QUERY_LOG_CREATED ON QUERY_LOG(CREATED_AT);
 -- Comentarios para documentación
 COMMENT ON TABLE QUERY_LOG IS 'Registro de todas las consultas ejecutadas en la API';
 COMMENT ON COLUMN QUERY_LOG.LOG_ID IS 'ID único del log';
-COMMENT ON COLUMN QUERY_LOG.QUERY_TYPE IS 'Tipo de operación: QUERY, EXEC, PROCEDURE';
+COMMENT ON COLUMN QUERY_LOG.QUERY_TYPE IS 'Tipo de operación: QUERY, EXEC, PROCEDURE, TABLE_FUNCTION';
 COMMENT ON COLUMN QUERY_LOG.QUERY_TEXT IS 'Texto de la consulta o nombre del procedimiento';
 COMMENT ON COLUMN QUERY_LOG.PARAMS IS 'Parámetros de la consulta en formato JSON';
 COMMENT ON COLUMN QUERY_LOG.EXECUTION_TIME IS 'Momento de ejecución';
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// statusRule convierte un valor OUT de estado en una respuesta HTTP de error (variable API_STATUS_RULES).
+// Ejemplo: "P_COD_ERROR != 0 => 422 P_MSG_ERROR" o, solo para un programa,
+// "PKG_VENTAS.REGISTRAR:P_ESTADO = 'RECHAZADO' => 409 P_MOTIVO".
+type statusRule struct {
+	Program  string // Programa al que aplica (vacío = todos); coincide también con el nombre sin esquema
+	Param    string // Parámetro OUT evaluado
+	Operator string // =, !=, <>, <, <=, >, >=
+	Value    string // Valor a comparar (numérico si ambos lados son números)
+	Status   int    // Código HTTP a responder
+	Message  string // Parámetro OUT con el mensaje de error (opcional)
+}
+
+// statusRules son las reglas cargadas al iniciar; las de un programa se evalúan antes que las globales
+var statusRules []statusRule
+
+var statusRulePattern = regexp.MustCompile(`^(?:([\w$#.]+)\s*:)?\s*([\w$#]+)\s*(!=|<>|<=|>=|=|<|>)\s*(.*?)\s*=>\s*(\d{3})(?:\s+([\w$#]+))?$`)
+
+// parseStatusRules interpreta API_STATUS_RULES: reglas separadas por ';' con el formato
+// [PROGRAMA:]PARAMETRO OPERADOR VALOR => HTTP [PARAMETRO_MENSAJE]
+func parseStatusRules(spec string) ([]statusRule, error) {
+	specific := []statusRule{}
+	global := []statusRule{}
+	for _, item := range strings.Split(spec, ";") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		m := statusRulePattern.FindStringSubmatch(item)
+		if m == nil {
+			return nil, fmt.Errorf("regla inválida '%s' (formato: [PROGRAMA:]PARAMETRO OPERADOR VALOR => HTTP [PARAMETRO_MENSAJE])", item)
+		}
+		status, _ := strconv.Atoi(m[5])
+		if status < 400 || status > 599 {
+			return nil, fmt.Errorf("regla inválida '%s': el código HTTP debe ser de error (4xx o 5xx)", item)
+		}
+		value := m[4]
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = value[1 : len(value)-1]
+		}
+		rule := statusRule{
+			Program:  strings.ToUpper(m[1]),
+			Param:    strings.ToUpper(m[2]),
+			Operator: m[3],
+			Value:    value,
+			Status:   status,
+			Message:  strings.ToUpper(m[6]),
+		}
+		if rule.Program != "" {
+			specific = append(specific, rule)
+		} else {
+			global = append(global, rule)
+		}
+	}
+	return append(specific, global...), nil
+}
+
+// appliesTo indica si la regla corresponde al programa llamado
+func (rule statusRule) appliesTo(req *ProcedureRequest) bool {
+	if rule.Program == "" {
+		return true
+	}
+	return programMatches(programKey(req.Schema, req.Name), rule.Program)
+}
+
+// matches compara el valor OUT con el de la regla; un OUT nulo nunca dispara la regla
+func (rule statusRule) matches(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	actual := fmt.Sprint(value)
+	cmp := 0
+	a, errA := strconv.ParseFloat(actual, 64)
+	b, errB := strconv.ParseFloat(rule.Value, 64)
+	if errA == nil && errB == nil {
+		if a < b {
+			cmp = -1
+		} else if a > b {
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(actual, rule.Value)
+	}
+	switch rule.Operator {
+	case "=":
+		return cmp == 0
+	case "!=", "<>":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+	return false
+}
+
+// lookupOut busca un valor OUT por nombre sin distinguir mayúsculas
+func lookupOut(out map[string]interface{}, name string) (interface{}, bool) {
+	for k, v := range out {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// checkStatusRules aplica las reglas de estado a los OUT de una llamada exitosa.
+// Retorna un procedureError con el código de la primera regla que se cumple, o nil.
+func checkStatusRules(req *ProcedureRequest, result *ProcedureResult) error {
+	for _, rule := range statusRules {
+		if !rule.appliesTo(req) {
+			continue
+		}
+		value, ok := lookupOut(result.Out, rule.Param)
+		if !ok || !rule.matches(value) {
+			continue
+		}
+		msg := fmt.Sprintf("%s retornó %s = %v", req.Name, rule.Param, value)
+		if rule.Message != "" {
+			if text, ok := lookupOut(result.Out, rule.Message); ok && text != nil && fmt.Sprint(text) != "" {
+				msg = fmt.Sprint(text)
+			}
+		}
+		return &procedureError{Status: rule.Status, Msg: msg, Output: result.Output, Out: result.Out}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestParseStatusRules(t *testing.T) {
+	rules, err := parseStatusRules("P_COD_ERROR != 0 => 422 P_MSG_ERROR; pkg_ventas.registrar:p_estado = 'RECHAZADO' => 409 p_motivo;;")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("se esperaban 2 reglas, hay %d", len(rules))
+	}
+	// Las reglas de un programa van antes que las globales
+	want := []statusRule{
+		{Program: "PKG_VENTAS.REGISTRAR", Param: "P_ESTADO", Operator: "=", Value: "RECHAZADO", Status: 409, Message: "P_MOTIVO"},
+		{Param: "P_COD_ERROR", Operator: "!=", Value: "0", Status: 422, Message: "P_MSG_ERROR"},
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("regla %d = %+v, se esperaba %+v", i, rules[i], want[i])
+		}
+	}
+
+	for _, spec := range []string{
+		"P_COD = 1",
+		"P_COD ~ 1 => 400",
+		"P_COD = 1 => 200",
+		"P_COD = 1 => 600",
+		"P_COD = 1 => 40",
+	} {
+		if _, err := parseStatusRules(spec); err == nil {
+			t.Errorf("parseStatusRules(%q): se esperaba error", spec)
+		}
+	}
+}
+
+func TestStatusRuleMatches(t *testing.T) {
+	tests := []struct {
+		op, ruleValue string
+		value         interface{}
+		want          bool
+	}{
+		{"=", "0", 0, true},
+		{"=", "0", "0.0", true}, // Comparación numérica
+		{"!=", "0", int64(-1), true},
+		{"<>", "0", 0, false},
+		{">", "10", 9.5, false},
+		{">=", "10", "10", true},
+		{"<", "10", "9", true},   // Numérica, no "9" > "10" como texto
+		{"<=", "B", "A", true},   // Texto
+		{"=", "OK", "ok", false}, // Distingue mayúsculas
+		{"=", "", nil, false},    // Un OUT nulo nunca dispara la regla
+		{"!=", "0", nil, false},
+	}
+	for _, tt := range tests {
+		rule := statusRule{Operator: tt.op, Value: tt.ruleValue}
+		if got := rule.matches(tt.value); got != tt.want {
+			t.Errorf("%v %s %q = %v, se esperaba %v", tt.value, tt.op, tt.ruleValue, got, tt.want)
+		}
+	}
+}
+
+func TestStatusRuleAppliesTo(t *testing.T) {
+	rule := statusRule{Program: "PKG_VENTAS.REGISTRAR"}
+	for _, req := range []ProcedureRequest{
+		{Name: "pkg_ventas.registrar"},
+		{Schema: "ventas", Name: "PKG_VENTAS.REGISTRAR"},
+	} {
+		if !rule.appliesTo(&req) {
+			t.Errorf("la regla debería aplicar a %s.%s", req.Schema, req.Name)
+		}
+	}
+	if rule.appliesTo(&ProcedureRequest{Name: "OTRO_PKG_VENTAS.REGISTRAR"}) {
+		t.Error("la regla no debería aplicar a OTRO_PKG_VENTAS.REGISTRAR")
+	}
+	if !(statusRule{}).appliesTo(&ProcedureRequest{Name: "CUALQUIERA"}) {
+		t.Error("una regla global aplica a todos los programas")
+	}
+}
+
+func TestCheckStatusRules(t *testing.T) {
+	saved := statusRules
+	defer func() { statusRules = saved }()
+	var err error
+	if statusRules, err = parseStatusRules("P_COD != 0 => 422 P_MSG; PKG.PROC:P_COD = 5 => 409"); err != nil {
+		t.Fatal(err)
+	}
+
+	req := &ProcedureRequest{Name: "PKG.PROC"}
+	ok := &ProcedureResult{Out: map[string]interface{}{"p_cod": 0}}
+	if err := checkStatusRules(req, ok); err != nil {
+		t.Errorf("no debería rechazar: %v", err)
+	}
+
+	// La regla del programa se evalúa antes que la global
+	err = checkStatusRules(req, &ProcedureResult{Out: map[string]interface{}{"P_COD": 5, "P_MSG": "no"}})
+	var perr *procedureError
+	if !errors.As(err, &perr) || perr.Status != http.StatusConflict || perr.Msg != "PKG.PROC retornó P_COD = 5" {
+		t.Errorf("se esperaba 409 de la regla del programa, se obtuvo %v", err)
+	}
+
+	// Mensaje tomado del parámetro OUT indicado
+	err = checkStatusRules(&ProcedureRequest{Name: "OTRO"}, &ProcedureResult{Out: map[string]interface{}{"P_COD": 5, "P_MSG": "Sin stock"}})
+	if !errors.As(err, &perr) || perr.Status != http.StatusUnprocessableEntity || perr.Msg != "Sin stock" || perr.Out["P_COD"] != 5 {
+		t.Errorf("se esperaba 422 'Sin stock', se obtuvo %v", err)
+	}
+}
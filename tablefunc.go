@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Tamaño de página por defecto y máximo de /table-function
+const (
+	tableFunctionDefaultLimit = 100
+	tableFunctionMaxLimit     = 5000
+)
+
+// tableFunctionRowNum es la columna auxiliar usada para paginar con ROWNUM
+const tableFunctionRowNum = "RN__"
+
+// TableFunctionRequest es el cuerpo de /table-function
+type TableFunctionRequest struct {
+	Name   string           `json:"name"`
+	Schema string           `json:"schema,omitempty"`
+	Params []ProcedureParam `json:"params"`
+	Limit  int              `json:"limit,omitempty"`  // Filas por página (por defecto 100, máximo 5000)
+	Offset int              `json:"offset,omitempty"` // Filas a saltar
+}
+
+// tableFunctionHandler ejecuta una función de tabla (pipelined o que retorna una colección)
+// como SELECT * FROM TABLE(fn(:1, ...)) y retorna las filas paginadas
+func tableFunctionHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(&w, r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite POST"})
+		return
+	}
+
+	var req TableFunctionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
+		return
+	}
+	if req.Limit <= 0 {
+		req.Limit = tableFunctionDefaultLimit
+	}
+	if req.Limit > tableFunctionMaxLimit {
+		req.Limit = tableFunctionMaxLimit
+	}
+	if req.Offset < 0 {
+		req.Offset = 0
+	}
+	for _, p := range req.Params {
+		if p.IsOut() {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("El parámetro '%s' es OUT: las funciones de tabla solo admiten parámetros IN", p.Name)})
+			return
+		}
+	}
+
+	// Se valida como una función cuyo retorno (la colección) no se enlaza
+	preq := ProcedureRequest{
+		Name:       req.Name,
+		Schema:     req.Schema,
+		IsFunction: true,
+		Params:     append([]ProcedureParam{{Name: "return", Direction: "OUT"}}, req.Params...),
+	}
+	if err := prepareProcedureRequest(r.Context(), &preq); err != nil {
+		w.WriteHeader(procedureErrorStatus(err))
+		json.NewEncoder(w).Encode(procedureErrorBody(err))
+		return
+	}
+
+	args := []interface{}{}
+	placeholders := []string{}
+	for i, p := range preq.Params[1:] {
+		arg, conv, err := bindIn(p)
+		if err == nil && conv != nil {
+			err = fmt.Errorf("Parámetro '%s': el tipo '%s' no se puede usar en SQL", p.Name, p.Type)
+		}
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		placeholder := fmt.Sprintf(":%d", len(args)+1)
+		if preq.argNames != nil && preq.argNames[i+1] != "" {
+			placeholder = fmt.Sprintf("%s => %s", preq.argNames[i+1], placeholder)
+		}
+		placeholders = append(placeholders, placeholder)
+		args = append(args, arg)
+	}
+
+	// Paginación con ROWNUM (compatible con 11g); se pide una fila extra para saber si hay más
+	inner := fmt.Sprintf("SELECT * FROM TABLE(%s(%s))", formatObjectName(req.Schema, req.Name), strings.Join(placeholders, ", "))
+	query := fmt.Sprintf("SELECT * FROM (SELECT q.*, ROWNUM AS %s FROM (%s) q WHERE ROWNUM <= %d) WHERE %s > %d",
+		tableFunctionRowNum, inner, req.Offset+req.Limit+1, tableFunctionRowNum, req.Offset)
+	log.Printf("[TABLE-FUNCTION] SQL generado: %s", inner)
+
+	startExec := time.Now()
+	paramsJSON, _ := json.Marshal(req.Params)
+	qlog := &QueryLog{
+		ID:            generateID(),
+		QueryType:     "TABLE_FUNCTION",
+		QueryText:     inner,
+		Params:        string(paramsJSON),
+		ExecutionTime: startExec,
+		UserIP:        r.RemoteAddr,
+	}
+
+	rows, err := db.QueryContext(r.Context(), query, args...)
+	var results []map[string]interface{}
+	if err == nil {
+		results, err = scanRowMaps(rows, 0)
+		rows.Close()
+	}
+	qlog.Duration = time.Since(startExec).String()
+	if err != nil {
+		qlog.Success = false
+		qlog.ErrorMsg = err.Error()
+		go saveQueryLog(qlog)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": describeCallError(&preq, err)})
+		return
+	}
+
+	hasMore := len(results) > req.Limit
+	if hasMore {
+		results = results[:req.Limit]
+	}
+	for _, row := range results {
+		delete(row, tableFunctionRowNum)
+	}
+
+	qlog.Success = true
+	qlog.RowsAffected = int64(len(results))
+	go saveQueryLog(qlog)
+
+	resp := map[string]interface{}{
+		"results":  results,
+		"offset":   req.Offset,
+		"limit":    req.Limit,
+		"has_more": hasMore,
+	}
+	if hasMore {
+		resp["next_offset"] = req.Offset + req.Limit
+	}
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getTimestampInputFormats retorna los formatos ISO-8601 aceptados para TIMESTAMP.
+// Las fracciones de segundo se aceptan siempre (time.Parse las admite tras los segundos).
+func getTimestampInputFormats() []string {
+	return []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02T15:04",
+	}
+}
+
+// parseTimestampParam interpreta un valor ISO-8601 (con fracciones y offset opcionales).
+// Sin offset la hora se toma tal cual (UTC en timestamptz). También acepta los formatos de fecha.
+func parseTimestampParam(value interface{}) (time.Time, error) {
+	if t, ok := value.(time.Time); ok {
+		return t, nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("se esperaba un texto ISO-8601")
+	}
+	s = strings.TrimSpace(s)
+	for _, layout := range getTimestampInputFormats() {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := parseDateParam(s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("no se pudo parsear timestamp (usa ISO-8601, p.ej. 2024-03-01T10:30:00.123456-03:00)")
+}
+
+// formatTimestampOutput formatea un TIMESTAMP de salida en ISO-8601 sin zona horaria
+func formatTimestampOutput(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.999999999")
+}
+
+// formatTimestampTZOutput formatea un TIMESTAMP WITH TIME ZONE de salida en ISO-8601 con offset
+func formatTimestampTZOutput(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+// isoDurationPattern reconoce duraciones ISO-8601: [-]P[nY][nM][nD][T[nH][nM][n[.f]S]]
+var isoDurationPattern = regexp.MustCompile(`^(-)?P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// sqlIntervalDSPattern y sqlIntervalYMPattern reconocen el formato de TO_CHAR de un INTERVAL
+var (
+	sqlIntervalDSPattern = regexp.MustCompile(`^([+-])?(\d+) (\d+):(\d+):(\d+)(?:\.(\d+))?$`)
+	sqlIntervalYMPattern = regexp.MustCompile(`^([+-])?(\d+)-(\d+)$`)
+)
+
+// intervalDSConversion e intervalYMConversion enlazan INTERVAL como texto (formato SQL)
+var (
+	intervalDSConversion = &bindConversion{
+		Decl: "INTERVAL DAY(9) TO SECOND(9)",
+		In:   "TO_DSINTERVAL(%[1]s)",
+		Out:  "TO_CHAR(%[1]s)",
+	}
+	intervalYMConversion = &bindConversion{
+		Decl: "INTERVAL YEAR(9) TO MONTH",
+		In:   "TO_YMINTERVAL(%[1]s)",
+		Out:  "TO_CHAR(%[1]s)",
+	}
+)
+
+// isYearMonthInterval indica si el tipo Oracle declarado es INTERVAL YEAR TO MONTH
+func isYearMonthInterval(oracleType string) bool {
+	return strings.HasPrefix(strings.ToUpper(oracleType), "INTERVAL YEAR")
+}
+
+// parseIntervalParam convierte una duración ISO-8601 al texto que aceptan TO_DSINTERVAL/TO_YMINTERVAL.
+// Retorna si es YEAR TO MONTH; no se pueden mezclar años/meses con días/horas.
+func parseIntervalParam(value interface{}) (string, bool, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", false, fmt.Errorf("se esperaba una duración ISO-8601 (p.ej. P1DT2H30M)")
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	m := isoDurationPattern.FindStringSubmatch(s)
+	if m == nil || s == "P" || strings.HasSuffix(s, "T") {
+		return "", false, fmt.Errorf("duración inválida '%s' (usa ISO-8601, p.ej. P1DT2H30M o P1Y2M)", s)
+	}
+	sign := ""
+	if m[1] != "" {
+		sign = "-"
+	}
+	num := func(i int) int64 {
+		n, _ := strconv.ParseInt(m[i], 10, 64)
+		return n
+	}
+
+	hasYM := m[2] != "" || m[3] != ""
+	hasDS := m[4] != "" || m[5] != "" || m[6] != "" || m[7] != ""
+	if hasYM && hasDS {
+		return "", false, fmt.Errorf("duración inválida '%s': Oracle no permite mezclar años/meses con días/horas", s)
+	}
+	if hasYM {
+		months := num(2)*12 + num(3)
+		return fmt.Sprintf("%s%d-%d", sign, months/12, months%12), true, nil
+	}
+
+	secs, _ := strconv.ParseFloat(m[7], 64)
+	whole, frac := math.Modf(secs)
+	nanos := int64(math.Round(frac * 1e9))
+	if nanos >= 1e9 {
+		// Más de 9 decimales que redondean al segundo siguiente (p.ej. PT1.9999999999S)
+		whole, nanos = whole+1, nanos-1e9
+	}
+	total := ((num(4)*24+num(5))*60+num(6))*60 + int64(whole)
+	return fmt.Sprintf("%s%d %02d:%02d:%02d.%09d", sign, total/86400, total%86400/3600, total%3600/60, total%60,
+		nanos), false, nil
+}
+
+// formatIntervalOutput convierte el TO_CHAR de un INTERVAL a duración ISO-8601
+func formatIntervalOutput(s string) interface{} {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if m := sqlIntervalYMPattern.FindStringSubmatch(s); m != nil {
+		years, _ := strconv.Atoi(m[2])
+		months, _ := strconv.Atoi(m[3])
+		iso := "P"
+		if years > 0 {
+			iso += fmt.Sprintf("%dY", years)
+		}
+		if months > 0 || years == 0 {
+			iso += fmt.Sprintf("%dM", months)
+		}
+		if m[1] == "-" {
+			iso = "-" + iso
+		}
+		return iso
+	}
+	if m := sqlIntervalDSPattern.FindStringSubmatch(s); m != nil {
+		days, _ := strconv.Atoi(m[2])
+		hours, _ := strconv.Atoi(m[3])
+		mins, _ := strconv.Atoi(m[4])
+		secs := m[5]
+		if frac := strings.TrimRight(m[6], "0"); frac != "" {
+			secs += "." + frac
+		}
+		secs = strings.TrimLeft(secs, "0")
+		if secs == "" || secs[0] == '.' {
+			secs = "0" + secs
+		}
+
+		iso := "P"
+		if days > 0 {
+			iso += fmt.Sprintf("%dD", days)
+		}
+		timePart := ""
+		if hours > 0 {
+			timePart += fmt.Sprintf("%dH", hours)
+		}
+		if mins > 0 {
+			timePart += fmt.Sprintf("%dM", mins)
+		}
+		if secs != "0" || (days == 0 && timePart == "") {
+			timePart += secs + "S"
+		}
+		if timePart != "" {
+			iso += "T" + timePart
+		}
+		if m[1] == "-" {
+			iso = "-" + iso
+		}
+		return iso
+	}
+	// Formato inesperado: retornar el texto de Oracle
+	return s
+}
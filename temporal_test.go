@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseIntervalParam(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ym   bool
+	}{
+		{"P1DT2H30M", "1 02:30:00.000000000", false},
+		{"PT90M", "0 01:30:00.000000000", false},
+		{"PT36H", "1 12:00:00.000000000", false},
+		{"pt1.5s", "0 00:00:01.500000000", false},
+		{"-P2D", "-2 00:00:00.000000000", false},
+		{"PT0.123456789S", "0 00:00:00.123456789", false},
+		// El redondeo a nanosegundos pasa al segundo (y al minuto) siguiente
+		{"PT1.9999999999S", "0 00:00:02.000000000", false},
+		{"PT59.9999999999S", "0 00:01:00.000000000", false},
+		{"P1Y2M", "1-2", true},
+		{"P14M", "1-2", true},
+		{"-P1Y", "-1-0", true},
+	}
+	for _, tt := range tests {
+		got, ym, err := parseIntervalParam(tt.in)
+		if err != nil || got != tt.want || ym != tt.ym {
+			t.Errorf("parseIntervalParam(%q) = %q, %v, %v; se esperaba %q, %v", tt.in, got, ym, err, tt.want, tt.ym)
+		}
+	}
+
+	for _, in := range []interface{}{"", "P", "PT", "P1Y2D", "1 day", "P1H", 10} {
+		if got, _, err := parseIntervalParam(in); err == nil {
+			t.Errorf("parseIntervalParam(%v) = %q; se esperaba error", in, got)
+		}
+	}
+}
+
+func TestFormatIntervalOutput(t *testing.T) {
+	tests := map[string]interface{}{
+		"+000000001 02:30:00.000000000": "P1DT2H30M",
+		"+000000000 00:00:01.500000000": "PT1.5S",
+		"+000000000 00:00:00.000000000": "PT0S",
+		"-000000002 00:00:00.000000000": "-P2D",
+		"+000000000 00:00:00.000000010": "PT0.00000001S",
+		"+000000001-02":                 "P1Y2M",
+		"+000000000-00":                 "P0M",
+		"-000000001-00":                 "-P1Y",
+		"":                              nil,
+		"texto":                         "texto",
+	}
+	for in, want := range tests {
+		if got := formatIntervalOutput(in); got != want {
+			t.Errorf("formatIntervalOutput(%q) = %v, se esperaba %v", in, got, want)
+		}
+	}
+}
+
+// Lo que se envía a Oracle y vuelve con TO_CHAR debe dar la misma duración
+func TestIntervalRoundTrip(t *testing.T) {
+	for _, iso := range []string{"P1DT2H30M", "PT1.5S", "-P2D", "P3DT4H5M6.789S", "P1Y2M", "P2Y", "-P5M"} {
+		text, _, err := parseIntervalParam(iso)
+		if err != nil {
+			t.Fatalf("parseIntervalParam(%q): %v", iso, err)
+		}
+		if got := formatIntervalOutput(text); got != iso {
+			t.Errorf("%q -> %q -> %v", iso, text, got)
+		}
+	}
+}
+
+func TestParseTimestampParam(t *testing.T) {
+	tests := map[string]time.Time{
+		"2024-03-01T10:30:00.123456-03:00": time.Date(2024, 3, 1, 13, 30, 0, 123456000, time.UTC),
+		"2024-03-01T10:30:00Z":             time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"2024-03-01 10:30:00":              time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+		"2024-03-01T10:30":                 time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC),
+	}
+	for in, want := range tests {
+		got, err := parseTimestampParam(in)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseTimestampParam(%q) = %v, %v; se esperaba %v", in, got, err, want)
+		}
+	}
+	if _, err := parseTimestampParam("mañana"); err == nil {
+		t.Error("parseTimestampParam: se esperaba error")
+	}
+}
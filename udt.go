@@ -0,0 +1,467 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+// udtType describe un tipo definido por el usuario (colección u objeto) ya registrado en go-ora
+type udtType struct {
+	Owner        string
+	Name         string
+	IsCollection bool
+	ElemType     string    // Para colecciones: tipo de los elementos (NUMBER, VARCHAR2, DATE o un objeto)
+	Elem         *udtType  // Para colecciones de objetos/colecciones: tipo del elemento
+	Attrs        []udtAttr // Para objetos: atributos en orden
+	goType       reflect.Type
+}
+
+// udtAttr describe un atributo de un tipo objeto
+type udtAttr struct {
+	Name     string
+	TypeName string
+	Nested   *udtType // Si el atributo es a su vez un objeto o colección
+}
+
+// udtRegistry cachea los tipos ya registrados en el driver para no consultar el diccionario en cada llamada
+type udtRegistry struct {
+	types map[string]*udtType
+	mu    sync.Mutex
+}
+
+var udtTypes = &udtRegistry{
+	types: make(map[string]*udtType),
+}
+
+// isScalarOracleType indica si el tipo es escalar (soportado directamente por go-ora dentro de un UDT)
+func isScalarOracleType(typeName string) bool {
+	switch normalizeScalarType(typeName) {
+	case "NUMBER", "VARCHAR2", "NVARCHAR2", "DATE", "TIMESTAMP", "RAW", "CLOB", "NCLOB":
+		return true
+	}
+	return false
+}
+
+// normalizeScalarType reduce los sinónimos de tipos escalares a los nombres que entiende go-ora
+func normalizeScalarType(typeName string) string {
+	t := strings.ToUpper(strings.TrimSpace(typeName))
+	if idx := strings.Index(t, "("); idx != -1 {
+		t = strings.TrimSpace(t[:idx])
+	}
+	switch t {
+	case "INTEGER", "INT", "SMALLINT", "FLOAT", "DECIMAL", "NUMERIC", "BINARY_INTEGER", "PLS_INTEGER", "BINARY_DOUBLE", "BINARY_FLOAT":
+		return "NUMBER"
+	case "VARCHAR", "CHAR", "STRING":
+		return "VARCHAR2"
+	case "NCHAR":
+		return "NVARCHAR2"
+	}
+	return t
+}
+
+// scalarGoType retorna el tipo Go usado para un atributo o elemento escalar
+func scalarGoType(typeName string) reflect.Type {
+	switch normalizeScalarType(typeName) {
+	case "NUMBER":
+		return reflect.TypeOf(sql.NullFloat64{})
+	case "DATE", "TIMESTAMP":
+		return reflect.TypeOf(sql.NullTime{})
+	case "RAW":
+		return reflect.TypeOf([]byte{})
+	}
+	return reflect.TypeOf(sql.NullString{})
+}
+
+// splitTypeName separa "OWNER.TYPE" en sus partes; sin owner se usa el esquema de la sesión
+func splitTypeName(typeName string) (string, string) {
+	typeName = strings.ToUpper(strings.Trim(strings.TrimSpace(typeName), "\""))
+	if idx := strings.Index(typeName, "."); idx != -1 {
+		return strings.Trim(typeName[:idx], "\""), strings.Trim(typeName[idx+1:], "\"")
+	}
+	return "", typeName
+}
+
+// Resolve obtiene (y registra en go-ora si hace falta) el tipo indicado, p.ej. "T_NUM_LIST" o "HR.T_EMPLEADO"
+func (reg *udtRegistry) Resolve(typeName string) (*udtType, error) {
+	if db == nil {
+		return nil, fmt.Errorf("base de datos no disponible")
+	}
+	owner, name := splitTypeName(typeName)
+	if name == "" {
+		return nil, fmt.Errorf("nombre de tipo vacío")
+	}
+	if owner == "" {
+		if err := db.QueryRow("SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM DUAL").Scan(&owner); err != nil {
+			return nil, fmt.Errorf("no se pudo determinar el esquema actual: %v", err)
+		}
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	return reg.resolveLocked(owner, name, 0)
+}
+
+func (reg *udtRegistry) resolveLocked(owner, name string, depth int) (*udtType, error) {
+	key := owner + "." + name
+	if t, ok := reg.types[key]; ok {
+		return t, nil
+	}
+	if depth > 10 {
+		return nil, fmt.Errorf("tipo %s demasiado anidado", key)
+	}
+
+	var typeCode string
+	err := db.QueryRow("SELECT TYPECODE FROM ALL_TYPES WHERE OWNER = :1 AND TYPE_NAME = :2", owner, name).Scan(&typeCode)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("tipo '%s' no encontrado", key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &udtType{Owner: owner, Name: name}
+	switch typeCode {
+	case "COLLECTION":
+		var elemOwner sql.NullString
+		var elemType string
+		err := db.QueryRow(`SELECT ELEM_TYPE_OWNER, ELEM_TYPE_NAME FROM ALL_COLL_TYPES
+			WHERE OWNER = :1 AND TYPE_NAME = :2`, owner, name).Scan(&elemOwner, &elemType)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo colección %s: %v", key, err)
+		}
+		t.IsCollection = true
+		t.ElemType = elemType
+		if elemOwner.Valid && elemOwner.String != "" {
+			// Colección de objetos: registrar el objeto junto con su tipo colección
+			elem, err := reg.resolveLocked(elemOwner.String, elemType, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			if elem.IsCollection {
+				return nil, fmt.Errorf("colecciones de colecciones no soportadas (%s)", key)
+			}
+			t.Elem = elem
+			t.goType = reflect.SliceOf(elem.goType)
+			if err := go_ora.RegisterTypeWithOwner(db, elem.Owner, elem.Name, name, reflect.New(elem.goType).Elem().Interface()); err != nil {
+				return nil, fmt.Errorf("error registrando colección %s: %v", key, err)
+			}
+		} else {
+			if !isScalarOracleType(elemType) {
+				return nil, fmt.Errorf("tipo de elemento no soportado en %s: %s", key, elemType)
+			}
+			t.goType = reflect.SliceOf(scalarGoType(elemType))
+			if err := go_ora.RegisterTypeWithOwner(db, owner, normalizeScalarType(elemType), name, nil); err != nil {
+				return nil, fmt.Errorf("error registrando colección %s: %v", key, err)
+			}
+		}
+	case "OBJECT":
+		rows, err := db.Query(`SELECT ATTR_NAME, ATTR_TYPE_OWNER, ATTR_TYPE_NAME FROM ALL_TYPE_ATTRS
+			WHERE OWNER = :1 AND TYPE_NAME = :2 ORDER BY ATTR_NO`, owner, name)
+		if err != nil {
+			return nil, fmt.Errorf("error leyendo atributos de %s: %v", key, err)
+		}
+		fields := []reflect.StructField{}
+		for rows.Next() {
+			var attrName, attrType string
+			var attrOwner sql.NullString
+			if err := rows.Scan(&attrName, &attrOwner, &attrType); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			attr := udtAttr{Name: attrName, TypeName: attrType}
+			var fieldType reflect.Type
+			if attrOwner.Valid && attrOwner.String != "" {
+				// Los tipos anidados deben estar registrados antes que el tipo que los contiene
+				nested, err := reg.resolveLocked(attrOwner.String, attrType, depth+1)
+				if err != nil {
+					rows.Close()
+					return nil, err
+				}
+				attr.Nested = nested
+				fieldType = nested.goType
+			} else {
+				fieldType = scalarGoType(attrType)
+			}
+			fields = append(fields, reflect.StructField{
+				Name: fmt.Sprintf("F%d", len(fields)),
+				Type: fieldType,
+				Tag:  reflect.StructTag(fmt.Sprintf(`udt:"%s"`, attrName)),
+			})
+			t.Attrs = append(t.Attrs, attr)
+		}
+		rows.Close()
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("tipo objeto %s sin atributos", key)
+		}
+		t.goType = reflect.StructOf(fields)
+		if err := go_ora.RegisterTypeWithOwner(db, owner, name, "", reflect.New(t.goType).Elem().Interface()); err != nil {
+			return nil, fmt.Errorf("error registrando tipo %s: %v", key, err)
+		}
+	default:
+		return nil, fmt.Errorf("tipo '%s' no soportado (TYPECODE=%s)", key, typeCode)
+	}
+
+	reg.types[key] = t
+	log.Printf("[UDT] Tipo registrado: %s", key)
+	return t, nil
+}
+
+// InArg convierte un valor JSON (array u objeto) en el argumento IN para go-ora
+func (t *udtType) InArg(value interface{}) (interface{}, error) {
+	v, err := t.fromJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	return go_ora.Object{Owner: t.Owner, Name: t.Name, Value: v.Interface()}, nil
+}
+
+// OutArg crea el destino para un parámetro OUT; el puntero retornado se lee con ToJSON tras ejecutar
+func (t *udtType) OutArg() (interface{}, reflect.Value) {
+	ptr := reflect.New(t.goType)
+	return go_ora.Object{Owner: t.Owner, Name: t.Name, Value: ptr.Interface()}, ptr
+}
+
+// fromJSON construye el valor Go (slice o struct dinámico) correspondiente al tipo
+func (t *udtType) fromJSON(value interface{}) (reflect.Value, error) {
+	if t.IsCollection {
+		items, ok := value.([]interface{})
+		if !ok {
+			if value == nil {
+				return reflect.MakeSlice(t.goType, 0, 0), nil
+			}
+			return reflect.Value{}, fmt.Errorf("se esperaba un array JSON para %s", t.Name)
+		}
+		slice := reflect.MakeSlice(t.goType, len(items), len(items))
+		for i, item := range items {
+			var elem reflect.Value
+			var err error
+			if t.Elem != nil {
+				elem, err = t.Elem.fromJSON(item)
+			} else {
+				elem, err = scalarFromJSON(t.ElemType, item)
+			}
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("%s[%d]: %v", t.Name, i, err)
+			}
+			slice.Index(i).Set(elem)
+		}
+		return slice, nil
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("se esperaba un objeto JSON para %s", t.Name)
+	}
+	// Los atributos se buscan sin distinguir mayúsculas/minúsculas
+	lowered := make(map[string]interface{}, len(obj))
+	for k, v := range obj {
+		lowered[strings.ToLower(k)] = v
+	}
+	for k := range lowered {
+		found := false
+		for _, attr := range t.Attrs {
+			if strings.ToLower(attr.Name) == k {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("atributo '%s' no existe en %s", k, t.Name)
+		}
+	}
+	st := reflect.New(t.goType).Elem()
+	for i, attr := range t.Attrs {
+		raw, present := lowered[strings.ToLower(attr.Name)]
+		if !present {
+			continue
+		}
+		var field reflect.Value
+		var err error
+		if attr.Nested != nil {
+			field, err = attr.Nested.fromJSON(raw)
+		} else {
+			field, err = scalarFromJSON(attr.TypeName, raw)
+		}
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("%s.%s: %v", t.Name, attr.Name, err)
+		}
+		st.Field(i).Set(field)
+	}
+	return st, nil
+}
+
+// scalarFromJSON convierte un valor JSON escalar al tipo Go usado por scalarGoType
+func scalarFromJSON(typeName string, value interface{}) (reflect.Value, error) {
+	switch normalizeScalarType(typeName) {
+	case "NUMBER":
+		n := sql.NullFloat64{}
+		switch v := value.(type) {
+		case nil:
+		case float64:
+			n = sql.NullFloat64{Float64: v, Valid: true}
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("valor numérico inválido: %q", v)
+			}
+			n = sql.NullFloat64{Float64: f, Valid: true}
+		case bool:
+			if v {
+				n = sql.NullFloat64{Float64: 1, Valid: true}
+			} else {
+				n = sql.NullFloat64{Float64: 0, Valid: true}
+			}
+		default:
+			return reflect.Value{}, fmt.Errorf("valor numérico inválido: %v", v)
+		}
+		return reflect.ValueOf(n), nil
+	case "DATE", "TIMESTAMP":
+		d := sql.NullTime{}
+		if value != nil {
+			parsed, err := parseDateParam(value)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("fecha inválida: %v", value)
+			}
+			d = sql.NullTime{Time: parsed, Valid: true}
+		}
+		return reflect.ValueOf(d), nil
+	case "RAW":
+		if value == nil {
+			return reflect.ValueOf([]byte(nil)), nil
+		}
+		return reflect.ValueOf([]byte(fmt.Sprintf("%v", value))), nil
+	}
+	s := sql.NullString{}
+	switch v := value.(type) {
+	case nil:
+	case string:
+		s = sql.NullString{String: v, Valid: true}
+	case map[string]interface{}, []interface{}:
+		return reflect.Value{}, fmt.Errorf("se esperaba un valor escalar")
+	default:
+		s = sql.NullString{String: fmt.Sprintf("%v", v), Valid: true}
+	}
+	return reflect.ValueOf(s), nil
+}
+
+// ToJSON convierte el valor leído de Oracle (slice o struct dinámico) a tipos JSON
+func (t *udtType) ToJSON(v reflect.Value) interface{} {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if t.IsCollection {
+		if v.IsNil() {
+			return nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if t.Elem != nil {
+				items[i] = t.Elem.ToJSON(v.Index(i))
+			} else {
+				items[i] = scalarToJSON(v.Index(i).Interface())
+			}
+		}
+		return items
+	}
+	obj := make(map[string]interface{}, len(t.Attrs))
+	for i, attr := range t.Attrs {
+		if attr.Nested != nil {
+			obj[attr.Name] = attr.Nested.ToJSON(v.Field(i))
+		} else {
+			obj[attr.Name] = scalarToJSON(v.Field(i).Interface())
+		}
+	}
+	return obj
+}
+
+// scalarToJSON convierte los tipos sql.Null* a valores JSON
+func scalarToJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case sql.NullFloat64:
+		if v.Valid {
+			return v.Float64
+		}
+		return nil
+	case sql.NullString:
+		if v.Valid {
+			return v.String
+		}
+		return nil
+	case sql.NullTime:
+		if v.Valid {
+			return formatDateOutput(v.Time)
+		}
+		return nil
+	case time.Time:
+		return formatDateOutput(v)
+	case []byte:
+		if v == nil {
+			return nil
+		}
+		return string(v)
+	}
+	return value
+}
+
+// udtOutBind guarda el argumento y el destino de un parámetro OUT de tipo colección u objeto
+type udtOutBind struct {
+	typ *udtType
+	ptr reflect.Value
+	arg interface{}
+}
+
+// Value retorna el valor OUT ya convertido a JSON
+func (b *udtOutBind) Value() interface{} {
+	return b.typ.ToJSON(b.ptr)
+}
+
+// udtOutBindFor prepara el destino de un parámetro OUT con "type_name"
+func udtOutBindFor(typeName string) (*udtOutBind, error) {
+	udt, err := udtTypes.Resolve(typeName)
+	if err != nil {
+		return nil, err
+	}
+	arg, ptr := udt.OutArg()
+	return &udtOutBind{typ: udt, ptr: ptr, arg: arg}, nil
+}
+
+// udtBindArg construye el argumento de un parámetro con "type_name" (colección u objeto).
+// Para parámetros OUT retorna además el destino desde el que leer el resultado.
+func udtBindArg(typeName, direction string, value interface{}) (interface{}, *udtOutBind, error) {
+	if strings.ToUpper(direction) == "OUT" {
+		bind, err := udtOutBindFor(typeName)
+		if err != nil {
+			return nil, nil, err
+		}
+		return bind.arg, bind, nil
+	}
+	udt, err := udtTypes.Resolve(typeName)
+	if err != nil {
+		return nil, nil, err
+	}
+	arg, err := udt.InArg(value)
+	if err != nil {
+		return nil, nil, err
+	}
+	return arg, nil, nil
+}
+
+// isCompositeValue indica si el valor JSON es un array u objeto (requiere "type_name")
+func isCompositeValue(value interface{}) bool {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Valores por defecto de la entrega de webhooks (callback_url de /procedure/async)
+const (
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookRetryDelay  = 5 * time.Second
+	maxWebhookRetryDelay      = 10 * time.Minute
+	maxCallbackURLLength      = 2000 // Tamaño de ASYNC_JOBS.CALLBACK_URL
+	callbackLookupTimeout     = 5 * time.Second
+)
+
+// Estados de la entrega del webhook de un job
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookAttempt es un intento de entrega del webhook
+type WebhookAttempt struct {
+	Attempt    int       `json:"attempt"`
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Duration   string    `json:"duration"`
+}
+
+// WebhookState es el estado de la notificación del resultado de un job a su callback_url
+type WebhookState struct {
+	Status   string           `json:"status"` // pending, delivered, failed
+	Attempts []WebhookAttempt `json:"attempts"`
+}
+
+// WebhookSender entrega el JSON final de los jobs a su callback_url, firmado con HMAC-SHA256
+type WebhookSender struct {
+	secret      []byte
+	maxAttempts int
+	retryDelay  time.Duration // Espera antes del segundo intento; se duplica en cada reintento
+	client      *http.Client
+
+	// Destinos permitidos aunque resuelvan a direcciones internas (WEBHOOK_ALLOWED_HOSTS):
+	// nombres exactos, *.dominio o rangos CIDR
+	allowedHosts   []string
+	allowedDomains []string
+	allowedNets    []*net.IPNet
+}
+
+var webhooks *WebhookSender
+
+// newWebhookSenderFromEnv arma el emisor con WEBHOOK_SECRET, WEBHOOK_MAX_ATTEMPTS,
+// WEBHOOK_TIMEOUT, WEBHOOK_RETRY_DELAY y WEBHOOK_ALLOWED_HOSTS
+func newWebhookSenderFromEnv() (*WebhookSender, error) {
+	s := &WebhookSender{
+		secret:      []byte(os.Getenv("WEBHOOK_SECRET")),
+		maxAttempts: defaultWebhookMaxAttempts,
+		retryDelay:  defaultWebhookRetryDelay,
+	}
+	timeout := defaultWebhookTimeout
+
+	if value := strings.TrimSpace(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("WEBHOOK_MAX_ATTEMPTS debe ser un número entero mayor o igual a 1")
+		}
+		s.maxAttempts = n
+	}
+	durationEnv := func(name string, dest *time.Duration) error {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return fmt.Errorf("%s debe ser una duración positiva (p.ej. 10s, 1m)", name)
+		}
+		*dest = d
+		return nil
+	}
+	if err := durationEnv("WEBHOOK_TIMEOUT", &timeout); err != nil {
+		return nil, err
+	}
+	if err := durationEnv("WEBHOOK_RETRY_DELAY", &s.retryDelay); err != nil {
+		return nil, err
+	}
+	for _, entry := range strings.Split(os.Getenv("WEBHOOK_ALLOWED_HOSTS"), ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+		case strings.Contains(entry, "/"):
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("WEBHOOK_ALLOWED_HOSTS: rango inválido '%s' (usa p.ej. 10.0.0.0/8)", entry)
+			}
+			s.allowedNets = append(s.allowedNets, ipNet)
+		case strings.HasPrefix(entry, "*."):
+			s.allowedDomains = append(s.allowedDomains, entry[1:])
+		default:
+			s.allowedHosts = append(s.allowedHosts, entry)
+		}
+	}
+
+	// Cada conexión revisa la dirección ya resuelta: cubre redirecciones y cambios de DNS
+	// posteriores a la validación de callback_url
+	dialer := &net.Dialer{Timeout: timeout}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if s.hostAllowed(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		checked := *dialer
+		checked.Control = func(network, address string, _ syscall.RawConn) error {
+			ip, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return s.checkCallbackIP(net.ParseIP(ip))
+		}
+		return checked.DialContext(ctx, network, addr)
+	}
+	s.client = &http.Client{Timeout: timeout, Transport: transport}
+	return s, nil
+}
+
+// hostAllowed indica si el host está en WEBHOOK_ALLOWED_HOSTS por nombre o dominio
+func (s *WebhookSender) hostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range s.allowedHosts {
+		if host == h {
+			return true
+		}
+	}
+	for _, d := range s.allowedDomains {
+		if strings.HasSuffix(host, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCallbackIP rechaza las direcciones internas (loopback, privadas, link-local como
+// 169.254.169.254, sin especificar o multicast) salvo las de WEBHOOK_ALLOWED_HOSTS
+func (s *WebhookSender) checkCallbackIP(ip net.IP) error {
+	if ip == nil {
+		return fmt.Errorf("dirección inválida")
+	}
+	for _, n := range s.allowedNets {
+		if n.Contains(ip) {
+			return nil
+		}
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("la dirección %s es interna; agrégala a WEBHOOK_ALLOWED_HOSTS si el receptor es de confianza", ip)
+	}
+	return nil
+}
+
+// validateCallbackURL verifica que callback_url sea una URL http(s) absoluta, que su host no
+// resuelva a una dirección interna y que haya un secreto configurado para firmar las entregas
+func (s *WebhookSender) validateCallbackURL(raw string) error {
+	if len(s.secret) == 0 {
+		return fmt.Errorf("'callback_url' requiere configurar WEBHOOK_SECRET en el servidor")
+	}
+	if len(raw) > maxCallbackURLLength {
+		return fmt.Errorf("'callback_url' no puede superar %d caracteres", maxCallbackURLLength)
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("'callback_url' debe ser una URL http:// o https:// absoluta")
+	}
+
+	host := u.Hostname()
+	if s.hostAllowed(host) {
+		return nil
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), callbackLookupTimeout)
+		defer cancel()
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return fmt.Errorf("'callback_url': no se pudo resolver '%s': %v", host, err)
+		}
+		ips = ips[:0]
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if err := s.checkCallbackIP(ip); err != nil {
+			return fmt.Errorf("'callback_url' no permitida: %v", err)
+		}
+	}
+	return nil
+}
+
+// sign calcula la firma de una entrega: HMAC-SHA256 de "<timestamp>.<cuerpo>" en hexadecimal
+func (s *WebhookSender) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff retorna la espera antes del intento indicado (2 en adelante)
+func (s *WebhookSender) backoff(attempt int) time.Duration {
+	delay := s.retryDelay
+	for i := 2; i < attempt && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxWebhookRetryDelay {
+		delay = maxWebhookRetryDelay
+	}
+	return delay
+}
+
+// webhookPayload es el JSON que se entrega: el job final sin el estado del propio webhook
+func webhookPayload(job *AsyncJob) []byte {
+	snapshot := *job
+	snapshot.Webhook = nil
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		log.Printf("[WEBHOOK] Error serializando job %s: %v", job.ID, err)
+	}
+	return payload
+}
+
+// Deliver entrega payload a callbackURL reintentando con backoff exponencial hasta
+// maxAttempts; cada intento queda registrado en el job. firstAttempt permite continuar
+// una entrega interrumpida por un reinicio.
+func (s *WebhookSender) Deliver(jobID, callbackURL string, payload []byte, firstAttempt int) {
+	if firstAttempt > s.maxAttempts {
+		jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+			if j.Webhook != nil {
+				j.Webhook.Status = WebhookFailed
+			}
+		})
+		return
+	}
+	for attempt := firstAttempt; attempt <= s.maxAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(s.backoff(attempt))
+		}
+		if _, exists := jobManager.GetJob(jobID); !exists {
+			log.Printf("[WEBHOOK] Job %s eliminado; se abandona la entrega", jobID)
+			return
+		}
+
+		result := s.send(jobID, callbackURL, payload, attempt)
+		status := WebhookPending
+		if result.Error == "" {
+			status = WebhookDelivered
+		} else if attempt == s.maxAttempts {
+			status = WebhookFailed
+		}
+		jobManager.UpdateJob(jobID, func(j *AsyncJob) {
+			if j.Webhook == nil {
+				j.Webhook = &WebhookState{}
+			}
+			j.Webhook.Attempts = append(j.Webhook.Attempts, result)
+			j.Webhook.Status = status
+		})
+
+		switch status {
+		case WebhookDelivered:
+			log.Printf("[WEBHOOK] Job %s entregado a %s (intento %d)", jobID, callbackURL, attempt)
+			return
+		case WebhookFailed:
+			log.Printf("[WEBHOOK] Job %s: entrega a %s fallida tras %d intentos: %s", jobID, callbackURL, attempt, result.Error)
+			return
+		}
+		log.Printf("[WEBHOOK] Job %s: intento %d a %s fallido: %s", jobID, attempt, callbackURL, result.Error)
+	}
+}
+
+// send hace un intento de entrega; cualquier respuesta 2xx se considera entregada
+func (s *WebhookSender) send(jobID, callbackURL string, payload []byte, attempt int) WebhookAttempt {
+	start := time.Now()
+	result := WebhookAttempt{Attempt: attempt, Time: start}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = time.Since(start).String()
+		return result
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-oracle-api-webhook")
+	req.Header.Set("X-Webhook-Job-Id", jobID)
+	req.Header.Set("X-Webhook-Attempt", strconv.Itoa(attempt))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", s.sign(timestamp, payload))
+
+	resp, err := s.client.Do(req)
+	result.Duration = time.Since(start).String()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Error = fmt.Sprintf("respuesta HTTP %d", resp.StatusCode)
+	}
+	return result
+}
+
+// resumeWebhookDeliveries retoma al iniciar las entregas pendientes de los jobs de esta instancia
+func resumeWebhookDeliveries() {
+	count := 0
+	for _, job := range jobManager.GetAllJobs() {
+		jobManager.mu.RLock()
+		pending := job.CallbackURL != "" && job.Webhook != nil && job.Webhook.Status == WebhookPending &&
+			jobRecovery.owns(job)
+		var payload []byte
+		next := 0
+		if pending {
+			payload = webhookPayload(job)
+			next = len(job.Webhook.Attempts) + 1
+		}
+		jobManager.mu.RUnlock()
+		if pending {
+			go webhooks.Deliver(job.ID, job.CallbackURL, payload, next)
+			count++
+		}
+	}
+	if count > 0 {
+		log.Printf("[WEBHOOK] %d entregas pendientes retomadas", count)
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestWebhookSender(t *testing.T, allowed string) *WebhookSender {
+	t.Helper()
+	t.Setenv("WEBHOOK_SECRET", "secreto")
+	t.Setenv("WEBHOOK_ALLOWED_HOSTS", allowed)
+	s, err := newWebhookSenderFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestWebhookSign(t *testing.T) {
+	s := &WebhookSender{secret: []byte("secreto")}
+	body := []byte(`{"job_id":"abc"}`)
+	mac := hmac.New(sha256.New, []byte("secreto"))
+	mac.Write([]byte("1700000000." + string(body)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if got := s.sign("1700000000", body); got != want {
+		t.Errorf("sign = %s, se esperaba %s", got, want)
+	}
+	if s.sign("1700000001", body) == want {
+		t.Error("la firma debe depender del timestamp")
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	s := &WebhookSender{retryDelay: 5 * time.Second}
+	tests := map[int]time.Duration{
+		2:  5 * time.Second,
+		3:  10 * time.Second,
+		5:  40 * time.Second,
+		8:  320 * time.Second,
+		9:  maxWebhookRetryDelay,
+		40: maxWebhookRetryDelay,
+	}
+	for attempt, want := range tests {
+		if got := s.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, se esperaba %v", attempt, got, want)
+		}
+	}
+}
+
+func TestWebhookSenderFromEnvErrors(t *testing.T) {
+	for name, value := range map[string]string{
+		"WEBHOOK_MAX_ATTEMPTS":  "0",
+		"WEBHOOK_TIMEOUT":       "diez",
+		"WEBHOOK_RETRY_DELAY":   "-5s",
+		"WEBHOOK_ALLOWED_HOSTS": "10.0.0.0/99",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := newWebhookSenderFromEnv(); err == nil {
+				t.Errorf("%s=%s: se esperaba error", name, value)
+			}
+		})
+	}
+}
+
+func TestWebhookCheckCallbackIP(t *testing.T) {
+	s := newTestWebhookSender(t, "10.1.0.0/16")
+	tests := map[string]bool{
+		"127.0.0.1":       false,
+		"::1":             false,
+		"10.0.0.5":        false,
+		"172.16.3.4":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"fe80::1":         false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+		"fd00::1":         false,
+		"10.1.2.3":        true, // Rango permitido
+		"8.8.8.8":         true,
+		"2001:4860::8888": true,
+	}
+	for ip, ok := range tests {
+		if err := s.checkCallbackIP(net.ParseIP(ip)); (err == nil) != ok {
+			t.Errorf("checkCallbackIP(%s) = %v, se esperaba permitida=%v", ip, err, ok)
+		}
+	}
+	if err := s.checkCallbackIP(nil); err == nil {
+		t.Error("checkCallbackIP(nil): se esperaba error")
+	}
+}
+
+func TestWebhookHostAllowed(t *testing.T) {
+	s := newTestWebhookSender(t, " Interno.Local , *.corp.example ,10.0.0.0/8")
+	tests := map[string]bool{
+		"interno.local":         true,
+		"INTERNO.LOCAL.":        true,
+		"api.corp.example":      true,
+		"a.b.corp.example":      true,
+		"corp.example":          false,
+		"otrocorp.example":      false,
+		"interno.local.externo": false,
+		"10.0.0.1":              false, // Los rangos se revisan por dirección, no por nombre
+	}
+	for host, want := range tests {
+		if got := s.hostAllowed(host); got != want {
+			t.Errorf("hostAllowed(%q) = %v, se esperaba %v", host, got, want)
+		}
+	}
+}
+
+func TestWebhookValidateCallbackURL(t *testing.T) {
+	s := newTestWebhookSender(t, "interno.local")
+	tests := map[string]string{
+		"https://8.8.8.8/hook":                     "",
+		"http://interno.local:8080/hook":           "", // Permitido por nombre, no se resuelve
+		"ftp://8.8.8.8/hook":                       "http:// o https://",
+		"/hook":                                    "http:// o https://",
+		"http://127.0.0.1/hook":                    "interna",
+		"http://[::1]:9000/hook":                   "interna",
+		"http://169.254.169.254/latest/meta-data/": "interna",
+		"https://8.8.8.8/" + strings.Repeat("x", maxCallbackURLLength): "no puede superar",
+	}
+	for raw, want := range tests {
+		err := s.validateCallbackURL(raw)
+		if want == "" && err != nil {
+			t.Errorf("validateCallbackURL(%q) = %v, se esperaba nil", raw, err)
+		}
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("validateCallbackURL(%q) = %v, se esperaba %q", raw, err, want)
+		}
+	}
+
+	s.secret = nil
+	if err := s.validateCallbackURL("https://8.8.8.8/hook"); err == nil || !strings.Contains(err.Error(), "WEBHOOK_SECRET") {
+		t.Errorf("sin secreto: %v", err)
+	}
+}
+
+// El cliente revisa la dirección al conectar (cubre redirecciones y cambios de DNS)
+func TestWebhookClientRejectsInternalAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if resp, err := newTestWebhookSender(t, "").client.Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Fatal("se esperaba que el cliente rechace la conexión a loopback")
+	}
+	resp, err := newTestWebhookSender(t, "127.0.0.0/8,::1/128").client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("con el rango permitido la conexión debería funcionar: %v", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,648 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Límites y políticas de POST /workflows
+const (
+	maxWorkflowSteps       = 50
+	workflowOnFailureStop  = "stop"     // Al fallar un paso no se inician más pasos (por defecto)
+	workflowOnFailureCont  = "continue" // Se saltan solo los pasos que dependen del que falló
+	workflowStepWaiting    = "waiting"  // Esperando a sus dependencias
+	workflowStepSkipped    = "skipped"  // No se ejecutó (dependencia fallida, política stop o cancelación)
+	workflowProcNamePrefix = "workflow:"
+)
+
+var workflowStepIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,100}$`)
+
+// workflowStep es un paso de un workflow: una llamada como la de /procedure/async más sus dependencias
+type workflowStep struct {
+	ID string `json:"id"`
+	ProcedureRequest
+	DependsOn []string          `json:"depends_on,omitempty"`
+	Inputs    map[string]string `json:"inputs,omitempty"` // Parámetro IN => "paso.PARAMETRO_OUT"
+	Priority  int               `json:"priority,omitempty"`
+	Retry     *RetryPolicy      `json:"retry,omitempty"`
+	// fail (por defecto) o requeue si la instancia se detiene durante la ejecución
+	OnInterrupt string `json:"on_interrupt,omitempty"`
+}
+
+// workflowRequest es el cuerpo de POST /workflows; se guarda en PARAMS del job padre
+type workflowRequest struct {
+	Name        string         `json:"name,omitempty"`
+	OnFailure   string         `json:"on_failure,omitempty"` // stop (por defecto) o continue
+	Priority    int            `json:"priority,omitempty"`   // Prioridad de los pasos que no indican la suya
+	CallbackURL string         `json:"callback_url,omitempty"`
+	Steps       []workflowStep `json:"steps"`
+}
+
+// WorkflowState es el avance de un workflow, guardado en el job padre
+type WorkflowState struct {
+	Name      string               `json:"name,omitempty"`
+	OnFailure string               `json:"on_failure"`
+	Cancelled bool                 `json:"cancelled,omitempty"`
+	Steps     []*WorkflowStepState `json:"steps"`
+}
+
+// WorkflowStepState es el estado de un paso: waiting, skipped o el estado de su job
+type WorkflowStepState struct {
+	ID        string   `json:"id"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	Status    string   `json:"status"`
+	JobID     string   `json:"job_id,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+func (s *WorkflowStepState) isFinal() bool {
+	return s.Status == workflowStepSkipped || JobStatus(s.Status).isFinal()
+}
+
+func (s *WorkflowStepState) isFailed() bool {
+	return s.Status == string(JobStatusFailed) || s.Status == string(JobStatusCancelled) ||
+		s.Status == string(JobStatusInterrupted)
+}
+
+// copy retorna una copia independiente del estado (ver AsyncJob.snapshot)
+func (w *WorkflowState) copy() *WorkflowState {
+	c := *w
+	c.Steps = make([]*WorkflowStepState, len(w.Steps))
+	for i, s := range w.Steps {
+		step := *s
+		c.Steps[i] = &step
+	}
+	return &c
+}
+
+// step busca el estado de un paso por ID
+func (w *WorkflowState) step(id string) *WorkflowStepState {
+	for _, s := range w.Steps {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// WorkflowManager avanza los workflows: cuando termina el job de un paso inicia los pasos cuyas
+// dependencias se completaron. El estado se deriva del job padre y de los jobs de cada paso,
+// así que avanzar es idempotente y se puede retomar tras un reinicio (resumeWorkflows).
+type WorkflowManager struct {
+	mu sync.Mutex // Serializa el avance de los workflows
+}
+
+var workflowManager = &WorkflowManager{}
+
+// parseInput separa una referencia "paso.PARAMETRO_OUT"
+func parseInput(ref string) (step, param string, ok bool) {
+	idx := strings.Index(ref, ".")
+	if idx <= 0 || idx == len(ref)-1 {
+		return "", "", false
+	}
+	return ref[:idx], ref[idx+1:], true
+}
+
+// jobOuts retorna los valores OUT del result de un job de procedimiento (result.out).
+// Los jobs guardados antes de separar "out" tienen los OUT directamente en result.
+func jobOuts(result map[string]interface{}) map[string]interface{} {
+	if out, ok := result["out"].(map[string]interface{}); ok {
+		return out
+	}
+	return result
+}
+
+// validateWorkflow valida la estructura del workflow: IDs, dependencias sin ciclos e inputs que
+// refieren a pasos de los que se depende. Completa on_failure por defecto.
+func validateWorkflow(wf *workflowRequest) error {
+	if len(wf.Steps) == 0 {
+		return fmt.Errorf("'steps' no puede estar vacío")
+	}
+	if len(wf.Steps) > maxWorkflowSteps {
+		return fmt.Errorf("un workflow admite hasta %d pasos", maxWorkflowSteps)
+	}
+	switch wf.OnFailure {
+	case "":
+		wf.OnFailure = workflowOnFailureStop
+	case workflowOnFailureStop, workflowOnFailureCont:
+	default:
+		return fmt.Errorf("'on_failure' debe ser '%s' o '%s'", workflowOnFailureStop, workflowOnFailureCont)
+	}
+
+	index := make(map[string]int, len(wf.Steps))
+	for i, s := range wf.Steps {
+		if !workflowStepIDPattern.MatchString(s.ID) {
+			return fmt.Errorf("paso %d: 'id' obligatorio (letras, números, '_' o '-', hasta 100 caracteres)", i+1)
+		}
+		if _, dup := index[s.ID]; dup {
+			return fmt.Errorf("paso '%s' repetido", s.ID)
+		}
+		index[s.ID] = i
+	}
+	for _, s := range wf.Steps {
+		for _, dep := range s.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return fmt.Errorf("paso '%s': depende de '%s', que no existe", s.ID, dep)
+			}
+			if dep == s.ID {
+				return fmt.Errorf("paso '%s': no puede depender de sí mismo", s.ID)
+			}
+		}
+	}
+
+	// Orden topológico (Kahn) para detectar ciclos
+	pending := make(map[string]int, len(wf.Steps))
+	dependents := make(map[string][]string)
+	for _, s := range wf.Steps {
+		pending[s.ID] = len(s.DependsOn)
+		for _, dep := range s.DependsOn {
+			dependents[dep] = append(dependents[dep], s.ID)
+		}
+	}
+	ready := []string{}
+	for _, s := range wf.Steps {
+		if pending[s.ID] == 0 {
+			ready = append(ready, s.ID)
+		}
+	}
+	ancestors := make(map[string]map[string]bool, len(wf.Steps))
+	visited := 0
+	for len(ready) > 0 {
+		id := ready[0]
+		ready = ready[1:]
+		visited++
+		anc := map[string]bool{}
+		for _, dep := range wf.Steps[index[id]].DependsOn {
+			anc[dep] = true
+			for a := range ancestors[dep] {
+				anc[a] = true
+			}
+		}
+		ancestors[id] = anc
+		for _, next := range dependents[id] {
+			pending[next]--
+			if pending[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if visited != len(wf.Steps) {
+		return fmt.Errorf("las dependencias de los pasos forman un ciclo")
+	}
+
+	// Los inputs solo pueden tomar OUT de pasos de los que se depende (directa o indirectamente)
+	for _, s := range wf.Steps {
+		for param, ref := range s.Inputs {
+			from, _, ok := parseInput(ref)
+			if !ok {
+				return fmt.Errorf("paso '%s': input '%s' inválido '%s' (formato paso.PARAMETRO_OUT)", s.ID, param, ref)
+			}
+			if !ancestors[s.ID][from] {
+				return fmt.Errorf("paso '%s': el input '%s' usa '%s', que no está entre sus dependencias", s.ID, param, from)
+			}
+		}
+	}
+	return nil
+}
+
+// stepRequest arma la llamada del paso agregando (o completando) los parámetros de inputs
+// con los valores dados; values nil deja los inputs sin valor (para validar antes de ejecutar)
+func stepRequest(s *workflowStep, values map[string]interface{}) ProcedureRequest {
+	req := s.ProcedureRequest
+	req.Params = append([]ProcedureParam(nil), s.Params...)
+	for param := range s.Inputs {
+		value := values[param]
+		found := false
+		for i := range req.Params {
+			if strings.EqualFold(req.Params[i].Name, param) {
+				req.Params[i].Value = value
+				found = true
+				break
+			}
+		}
+		if !found {
+			req.Params = append(req.Params, ProcedureParam{Name: param, Value: value})
+		}
+	}
+	return req
+}
+
+// workflowsHandler recibe POST /workflows
+func workflowsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORS(&w, r)
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Solo se permite POST"})
+		return
+	}
+
+	var wf workflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&wf); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "JSON inválido"})
+		return
+	}
+	badRequest := func(err error) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}
+	if err := validateWorkflow(&wf); err != nil {
+		badRequest(err)
+		return
+	}
+	priority, err := jobPriority(wf.Priority)
+	if err == nil && wf.CallbackURL != "" {
+		err = webhooks.validateCallbackURL(wf.CallbackURL)
+	}
+	if err != nil {
+		badRequest(err)
+		return
+	}
+	wf.Priority = priority
+
+	// Validar cada paso contra la firma del programa (los inputs todavía sin valor)
+	for i := range wf.Steps {
+		s := &wf.Steps[i]
+		if s.Priority == 0 {
+			s.Priority = wf.Priority
+		} else if _, err := jobPriority(s.Priority); err != nil {
+			badRequest(fmt.Errorf("paso '%s': %v", s.ID, err))
+			return
+		}
+		if s.Retry != nil {
+			if err := s.Retry.normalize(); err != nil {
+				badRequest(fmt.Errorf("paso '%s': %v", s.ID, err))
+				return
+			}
+		}
+		if s.OnInterrupt, err = parseOnInterrupt(s.OnInterrupt); err != nil {
+			badRequest(fmt.Errorf("paso '%s': %v", s.ID, err))
+			return
+		}
+		req := stepRequest(s, nil)
+		if err := prepareProcedureRequest(r.Context(), &req); err != nil {
+			body := procedureErrorBody(err)
+			body["error"] = fmt.Sprintf("paso '%s': %v", s.ID, err)
+			body["step"] = s.ID
+			w.WriteHeader(procedureErrorStatus(err))
+			json.NewEncoder(w).Encode(body)
+			return
+		}
+	}
+
+	if jobQueue.Full() {
+		writeQueueFull(w)
+		return
+	}
+
+	job, err := workflowManager.Submit(&wf, clientIP(r))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":           "accepted",
+		"job_id":           job.ID,
+		"message":          fmt.Sprintf("Workflow con %d pasos iniciado", len(wf.Steps)),
+		"check_status_url": fmt.Sprintf("/jobs/%s", job.ID),
+		"events_url":       fmt.Sprintf("/jobs/%s/events", job.ID),
+	})
+}
+
+// Submit crea el job padre del workflow e inicia los pasos sin dependencias;
+// submittedBy identifica al cliente y lo heredan los jobs de los pasos
+func (wm *WorkflowManager) Submit(wf *workflowRequest, submittedBy string) (*AsyncJob, error) {
+	state := &WorkflowState{Name: wf.Name, OnFailure: wf.OnFailure}
+	for _, s := range wf.Steps {
+		state.Steps = append(state.Steps, &WorkflowStepState{
+			ID:        s.ID,
+			DependsOn: s.DependsOn,
+			Status:    workflowStepWaiting,
+		})
+	}
+
+	raw, err := json.Marshal(wf)
+	if err != nil {
+		return nil, err
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+
+	name := wf.Name
+	if name == "" {
+		name = fmt.Sprintf("%d pasos", len(wf.Steps))
+	}
+	job := jobManager.CreateJob(workflowProcNamePrefix+name, params, jobOptions{
+		Priority:    wf.Priority,
+		CallbackURL: wf.CallbackURL,
+		Workflow:    state,
+		SubmittedBy: submittedBy,
+	})
+	jobManager.UpdateJob(job.ID, func(j *AsyncJob) {
+		j.Status = JobStatusRunning
+	})
+	log.Printf("[WORKFLOW] Workflow %s iniciado (%s, %d pasos)", job.ID, name, len(wf.Steps))
+
+	wm.advance(job.ID)
+	return job, nil
+}
+
+// stepFinished se llama cuando termina el job de un paso
+func (wm *WorkflowManager) stepFinished(parentID string) {
+	wm.advance(parentID)
+}
+
+// advance actualiza el estado de los pasos desde sus jobs, inicia los pasos listos, salta los
+// que ya no se pueden ejecutar y, cuando todos terminaron, cierra el job padre
+func (wm *WorkflowManager) advance(parentID string) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	parent, exists := jobManager.GetJob(parentID)
+	if !exists {
+		return
+	}
+	snap := parent.snapshotLocked()
+	if snap.Workflow == nil || snap.Status.isFinal() {
+		return
+	}
+	if !jobRecovery.owns(&snap) {
+		return // Lo avanza la instancia dueña (ver JobCluster.advanceWorkflows)
+	}
+	var wf workflowRequest
+	raw, _ := json.Marshal(snap.Params)
+	if err := json.Unmarshal(raw, &wf); err != nil {
+		log.Printf("[WORKFLOW] %s: definición ilegible: %v", parentID, err)
+		return
+	}
+	state := snap.Workflow
+
+	// Estado de los pasos en curso según sus jobs (y los OUT de los completados, para los inputs)
+	outs := map[string]map[string]interface{}{}
+	for _, s := range state.Steps {
+		if s.JobID == "" || s.Status == workflowStepSkipped {
+			continue
+		}
+		child, ok := jobManager.GetJob(s.JobID)
+		if !ok {
+			if !s.isFinal() {
+				s.Status = string(JobStatusFailed)
+				s.Error = "El job del paso fue eliminado"
+			}
+			continue
+		}
+		c := child.snapshotLocked()
+		s.Status = string(c.Status)
+		s.Error = c.Error
+		if c.Status == JobStatusCompleted {
+			outs[s.ID] = jobOuts(c.Result)
+		}
+	}
+
+	failed := func() bool {
+		for _, s := range state.Steps {
+			if s.isFailed() {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Iniciar o saltar pasos en espera hasta que no haya cambios
+	for changed := true; changed; {
+		changed = false
+		stop := state.Cancelled || (state.OnFailure == workflowOnFailureStop && failed())
+		for i, s := range state.Steps {
+			if s.Status != workflowStepWaiting {
+				continue
+			}
+			if stop {
+				s.Status = workflowStepSkipped
+				if state.Cancelled {
+					s.Error = "Workflow cancelado"
+				} else {
+					s.Error = "No se ejecutó porque falló un paso anterior"
+				}
+				changed = true
+				continue
+			}
+			ready := true
+			for _, dep := range s.DependsOn {
+				d := state.step(dep)
+				if d.isFailed() || d.Status == workflowStepSkipped {
+					s.Status = workflowStepSkipped
+					s.Error = fmt.Sprintf("No se ejecutó porque el paso '%s' no se completó", dep)
+					changed = true
+					ready = false
+					break
+				}
+				if d.Status != string(JobStatusCompleted) {
+					ready = false
+				}
+			}
+			if !ready || s.Status != workflowStepWaiting {
+				continue
+			}
+			wm.startStep(&snap, &wf.Steps[i], s, outs)
+			changed = true
+		}
+	}
+
+	// Con la política stop, los pasos encolados que no empezaron se cancelan y quedan saltados
+	if state.OnFailure == workflowOnFailureStop && failed() && !state.Cancelled {
+		for _, s := range state.Steps {
+			if s.Status == string(JobStatusPending) && cancelQueuedJob(s.JobID, "Cancelado porque falló un paso anterior del workflow") {
+				s.Status = workflowStepSkipped
+				s.Error = "No se ejecutó porque falló un paso anterior"
+			}
+		}
+	}
+
+	finished, anyFailed := 0, []string{}
+	for _, s := range state.Steps {
+		if s.isFinal() {
+			finished++
+		}
+		if s.isFailed() {
+			anyFailed = append(anyFailed, s.ID)
+		}
+	}
+	done := finished == len(state.Steps)
+
+	jobManager.UpdateJob(parentID, func(j *AsyncJob) {
+		if j.Status.isFinal() {
+			return
+		}
+		j.Workflow = state
+		j.Progress = finished * 100 / len(state.Steps)
+		if !done {
+			return
+		}
+		endTime := time.Now()
+		steps := map[string]interface{}{}
+		for _, s := range state.Steps {
+			entry := map[string]interface{}{"status": s.Status}
+			if s.JobID != "" {
+				entry["job_id"] = s.JobID
+			}
+			if out, ok := outs[s.ID]; ok {
+				entry["result"] = out
+			}
+			if s.Error != "" {
+				entry["error"] = s.Error
+			}
+			steps[s.ID] = entry
+		}
+		j.Result = map[string]interface{}{"steps": steps}
+		j.EndTime = &endTime
+		j.Duration = endTime.Sub(j.StartTime).String()
+		j.Progress = 100
+		switch {
+		case state.Cancelled:
+			j.Status = JobStatusCancelled
+			j.Error = "Workflow cancelado"
+		case len(anyFailed) > 0:
+			j.Status = JobStatusFailed
+			j.Error = "Fallaron los pasos: " + strings.Join(anyFailed, ", ")
+		default:
+			j.Status = JobStatusCompleted
+		}
+	})
+	if done {
+		log.Printf("[WORKFLOW] Workflow %s terminado", parentID)
+	}
+}
+
+// startStep resuelve los inputs del paso con los OUT de sus dependencias y crea su job
+func (wm *WorkflowManager) startStep(parent *AsyncJob, def *workflowStep, s *WorkflowStepState, outs map[string]map[string]interface{}) {
+	parentID := parent.ID
+	values := map[string]interface{}{}
+	for param, ref := range def.Inputs {
+		from, outName, _ := parseInput(ref)
+		value, ok := lookupOut(outs[from], outName)
+		if !ok {
+			s.Status = string(JobStatusFailed)
+			s.Error = fmt.Sprintf("El paso '%s' no retornó '%s' (input '%s')", from, outName, param)
+			return
+		}
+		values[param] = value
+	}
+
+	req := stepRequest(def, values)
+	job, err := submitProcedureJob(req, procedureRequestToParams(&req), jobOptions{
+		Priority:    def.Priority,
+		Retry:       def.Retry,
+		ParentID:    parentID,
+		Step:        def.ID,
+		OnInterrupt: def.OnInterrupt,
+		SubmittedBy: parent.SubmittedBy,
+	})
+	s.JobID = job.ID
+	if err != nil {
+		s.Status = string(JobStatusFailed)
+		s.Error = "No se pudo encolar: " + err.Error()
+		return
+	}
+	s.Status = string(JobStatusPending)
+	log.Printf("[WORKFLOW] %s: paso '%s' encolado como job %s", parentID, def.ID, job.ID)
+}
+
+// cancelQueuedJob cancela un job que todavía está en cola (en esta instancia o en la cola
+// compartida); retorna false si ya había empezado
+func cancelQueuedJob(id, reason string) bool {
+	if !jobQueue.Remove(id) {
+		job, ok := jobManager.GetJob(id)
+		if !ok || !jobCluster.shared() {
+			return false
+		}
+		if snap := job.snapshotLocked(); snap.Instance != "" || !jobCluster.claimUnclaimed(id) {
+			return false
+		}
+	}
+	endTime := time.Now()
+	jobManager.UpdateJob(id, func(j *AsyncJob) {
+		j.Status = JobStatusCancelled
+		j.Error = reason
+		j.EndTime = &endTime
+		j.Progress = 100
+	})
+	return true
+}
+
+// Cancel cancela un workflow: los pasos en espera se saltan, los encolados se cancelan y los
+// que están en ejecución se interrumpen. Retorna si quedan pasos en ejecución.
+func (wm *WorkflowManager) Cancel(parentID string) (running bool) {
+	wm.mu.Lock()
+	parent, exists := jobManager.GetJob(parentID)
+	if !exists {
+		wm.mu.Unlock()
+		return false
+	}
+	snap := parent.snapshotLocked()
+	if snap.Workflow == nil || snap.Status.isFinal() {
+		wm.mu.Unlock()
+		return false
+	}
+	state := snap.Workflow
+	state.Cancelled = true
+	for _, s := range state.Steps {
+		if s.JobID == "" || s.isFinal() {
+			continue
+		}
+		if wasPending, found := jobQueue.Cancel(s.JobID); found && !wasPending {
+			running = true
+		} else if !found && jobCluster.shared() {
+			// Paso en otra instancia o en la cola compartida
+			if status, _ := cancelJob(s.JobID); status == http.StatusAccepted {
+				running = true
+			}
+		} else if found {
+			endTime := time.Now()
+			jobManager.UpdateJob(s.JobID, func(j *AsyncJob) {
+				j.Status = JobStatusCancelled
+				j.Error = "Workflow cancelado"
+				j.EndTime = &endTime
+				j.Progress = 100
+			})
+		}
+	}
+	jobManager.UpdateJob(parentID, func(j *AsyncJob) {
+		j.Workflow = state
+	})
+	wm.mu.Unlock()
+
+	log.Printf("[WORKFLOW] Workflow %s cancelado", parentID)
+	wm.advance(parentID)
+	return running
+}
+
+// resumeWorkflows retoma al iniciar los workflows que quedaron en ejecución
+func resumeWorkflows() {
+	count := 0
+	for _, job := range jobManager.GetAllJobs() {
+		snap := job.snapshotLocked()
+		if snap.Workflow != nil && snap.Status == JobStatusRunning && jobRecovery.owns(&snap) {
+			workflowManager.advance(snap.ID)
+			count++
+		}
+	}
+	if count > 0 {
+		log.Printf("[WORKFLOW] %d workflows en ejecución retomados", count)
+	}
+}